@@ -0,0 +1,39 @@
+// Command fakegemini serves the geminitest fake Gemini API on a fixed
+// address for local runs and CI:
+//
+//	go run ./cmd/fakegemini -addr :9090
+//	JWT_ALLOW_DEV_SECRET=1 GOOGLE_API_KEY=fake GEMINI_BASE_URL=http://localhost:9090 \
+//	GEMINI_LIVE_URL=ws://localhost:9090/v1alpha/stream go run ./cmd/server
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/steveyiyo/hackyou-backend/internal/core/gemini/geminitest"
+)
+
+func main() {
+	addr := flag.String("addr", ":9090", "listen address")
+	tip := flag.String("tip", "Lift your chin a little.", "tip text returned as JSON")
+	text := flag.String("text", "", "plain-text reply instead of a JSON tip")
+	status := flag.Int("status", 0, "HTTP status to fail every call with, e.g. 503")
+	advice := flag.String("live-advice", "Move a step to the left.|Great, hold that pose.", "live advice, |-separated")
+	resetAfter := flag.Int("live-reset-after", 0, "drop the live connection every N frames")
+	flag.Parse()
+
+	s := geminitest.New()
+	r := geminitest.Response{Status: *status}
+	if *text != "" {
+		r.Text = *text
+	} else {
+		r.Tip = geminitest.Tip{Text: *tip}
+	}
+	s.SetDefault(r)
+	s.SetLive(geminitest.Live{Advice: strings.Split(*advice, "|"), ResetAfter: *resetAfter})
+
+	log.Printf("fake gemini on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, s.Handler()))
+}
@@ -0,0 +1,117 @@
+// Command schemagen writes the JSON Schema for server→client stream
+// messages defined in pkg/types.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/steveyiyo/hackyou-backend/pkg/types"
+)
+
+func main() {
+	out := flag.String("o", "api/stream.schema.json", "output file")
+	flag.Parse()
+
+	names := make([]string, 0, len(types.StreamMessages))
+	for k := range types.StreamMessages {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	defs := map[string]interface{}{}
+	var oneOf []interface{}
+	for _, name := range names {
+		t := reflect.TypeOf(types.StreamMessages[name])
+		s := schemaFor(t, defs)
+		s["properties"].(map[string]interface{})["type"] = map[string]interface{}{"const": name}
+		defs[t.Name()] = s
+		oneOf = append(oneOf, ref(t))
+	}
+
+	doc := map[string]interface{}{
+		"$schema":     "https://json-schema.org/draft/2020-12/schema",
+		"$id":         "https://hackyou.steveyi.net/schemas/stream.schema.json",
+		"title":       "Stream server messages",
+		"description": "Messages sent by the server on /v1/stream, protocol versions " + strconv.Itoa(types.StreamProtocolMin) + "-" + strconv.Itoa(types.StreamProtocolMax) + ".",
+		"oneOf":       oneOf,
+		"$defs":       defs,
+	}
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, append(b, '\n'), 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func ref(t reflect.Type) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/$defs/" + t.Name()}
+}
+
+func schemaFor(t reflect.Type, defs map[string]interface{}) map[string]interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemaFor(t.Elem(), defs)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaFor(t.Elem(), defs)}
+	case reflect.Struct:
+		props := map[string]interface{}{}
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			ft := f.Type
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && ft.Name() != "" {
+				if _, ok := defs[ft.Name()]; !ok {
+					defs[ft.Name()] = nil
+					defs[ft.Name()] = schemaFor(ft, defs)
+				}
+				props[name] = ref(ft)
+			} else {
+				props[name] = schemaFor(ft, defs)
+			}
+			if !strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Pointer {
+				required = append(required, name)
+			}
+		}
+		s := map[string]interface{}{"type": "object", "properties": props}
+		if len(required) > 0 {
+			s["required"] = required
+		}
+		return s
+	default:
+		return map[string]interface{}{}
+	}
+}
@@ -1,19 +1,62 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/steveyiyo/hackyou-backend/internal/config"
 	h "github.com/steveyiyo/hackyou-backend/internal/http"
+	"github.com/steveyiyo/hackyou-backend/internal/repo"
 
 	"github.com/joho/godotenv"
 )
 
+// shutdownTimeout bounds waiting for in-flight requests on SIGTERM.
+const shutdownTimeout = 10 * time.Second
+
 func main() {
 	_ = godotenv.Load()
-	cfg := config.Load()
-	r := h.NewRouter(cfg)
-	if err := r.Run(":" + cfg.Port); err != nil {
+	if err := run(config.Load()); err != nil {
 		log.Fatal(err)
 	}
 }
+
+// run serves until SIGINT or SIGTERM, then drains requests and writes out
+// the session store.
+func run(cfg config.Config) error {
+	sessions, err := repo.Open(cfg.SessionStore, cfg.SessionDir)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := sessions.Close(); err != nil {
+			log.Printf("session store: close: %v", err)
+		}
+	}()
+	r, err := h.NewRouter(cfg, sessions)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	srv := &http.Server{Addr: ":" + cfg.Port, Handler: r}
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	sctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(sctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const userKey = "auth.user_id"
+
+var errNoSubject = errors.New("token has no subject")
+
+// Verify checks an HS256 token signed with secret and returns its subject.
+func Verify(secret []byte, token string) (string, error) {
+	var claims jwt.RegisteredClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) {
+		return secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return "", err
+	}
+	if claims.Subject == "" {
+		return "", errNoSubject
+	}
+	return claims.Subject, nil
+}
+
+// Middleware rejects requests without a valid bearer token and stores the
+// token subject as the request's user ID.
+func Middleware(secret string) gin.HandlerFunc {
+	key := []byte(secret)
+	return func(c *gin.Context) {
+		tok := bearer(c.GetHeader("Authorization"))
+		if tok == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		sub, err := Verify(key, tok)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid_token"})
+			return
+		}
+		c.Set(userKey, sub)
+		c.Next()
+	}
+}
+
+// UserID returns the authenticated subject set by Middleware.
+func UserID(c *gin.Context) string { return c.GetString(userKey) }
+
+func bearer(h string) string {
+	const p = "Bearer "
+	if len(h) > len(p) && strings.EqualFold(h[:len(p)], p) {
+		return strings.TrimSpace(h[len(p):])
+	}
+	return ""
+}
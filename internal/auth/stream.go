@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrTokenMissing  = errors.New("stream token missing")
+	ErrTokenInvalid  = errors.New("stream token invalid")
+	ErrTokenExpired  = errors.New("stream token expired")
+	ErrTokenSession  = errors.New("stream token bound to another session")
+	ErrTokenReplayed = errors.New("stream token already used")
+)
+
+// StreamTokens issues and redeems short-lived, single-use tokens that allow
+// one WebSocket upgrade for one session. They are signed with a key derived
+// from the JWT secret so they cannot be used as bearer tokens and vice versa.
+type StreamTokens struct {
+	key []byte
+	ttl time.Duration
+
+	mu   sync.Mutex
+	used map[string]time.Time
+}
+
+func NewStreamTokens(secret string, ttl time.Duration) *StreamTokens {
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write([]byte("stream-token"))
+	return &StreamTokens{key: m.Sum(nil), ttl: ttl, used: map[string]time.Time{}}
+}
+
+func (s *StreamTokens) Issue(sessionID string) (string, time.Time, error) {
+	now := time.Now()
+	exp := now.Add(s.ttl)
+	t := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Subject:   sessionID,
+		ID:        uuid.NewString(),
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(exp),
+	})
+	tok, err := t.SignedString(s.key)
+	return tok, exp, err
+}
+
+// Check validates token for sessionID without using it up.
+func (s *StreamTokens) Check(token, sessionID string) error {
+	_, err := s.parse(token, sessionID)
+	return err
+}
+
+// Redeem validates token for sessionID and marks it used.
+func (s *StreamTokens) Redeem(token, sessionID string) error {
+	claims, err := s.parse(token, sessionID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, exp := range s.used {
+		if now.After(exp) {
+			delete(s.used, id)
+		}
+	}
+	if _, ok := s.used[claims.ID]; ok {
+		return ErrTokenReplayed
+	}
+	s.used[claims.ID] = claims.ExpiresAt.Time
+	return nil
+}
+
+// Release makes a redeemed token usable again, e.g. after the upgrade it
+// was redeemed for failed.
+func (s *StreamTokens) Release(token, sessionID string) {
+	claims, err := s.parse(token, sessionID)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.used, claims.ID)
+}
+
+func (s *StreamTokens) parse(token, sessionID string) (jwt.RegisteredClaims, error) {
+	var claims jwt.RegisteredClaims
+	if token == "" {
+		return claims, ErrTokenMissing
+	}
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) {
+		return s.key, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return claims, ErrTokenExpired
+	case err != nil || claims.ID == "":
+		return claims, ErrTokenInvalid
+	case claims.Subject != sessionID:
+		return claims, ErrTokenSession
+	}
+	return claims, nil
+}
@@ -1,20 +1,149 @@
 package config
 
-import "os"
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DevJWTSecret is the well-known secret for local runs. NewRouter only
+// accepts it, or an empty JWT_SECRET, with JWT_ALLOW_DEV_SECRET=1.
+const DevJWTSecret = "devsecret"
 
 type Config struct {
 	Port         string
 	JWTSecret    string
 	TTSBase      string
 	GeminiAPIKey string
+	GeminiModel  string
+	GeminiURL    string
+	LiveURL      string
+	LiveModel    string
+	LivePrompt   string
+	PromptDir    string
+	SessionStore string
+	SessionDir   string
+
+	// LiveMaxRetries bounds consecutive failed Live API reconnects before
+	// a stream stays on interval tips; zero retries until it closes.
+	LiveMaxRetries int
+
+	// LocaleFallback is tried in order for sessions in a locale the tip
+	// messages do not support.
+	LocaleFallback []string
+	TipHistory     int
+
+	TipCooldown    time.Duration
+	TipSimilarity  float64
+	TipHold        time.Duration
+	TipPraiseEvery time.Duration
+
+	CaptureCountdown time.Duration
+	ReadyStableTips  int
+	ReadyCooldown    time.Duration
+
+	CaptureMaxBytes    int64
+	CaptureRateTimeout time.Duration
+
+	FrameTypes   []string
+	FrameMaxDim  int
+	FrameQuality int
+
+	MotionThreshold int
+	MotionMaxReuse  time.Duration
+
+	TipCacheSize int
+	TipCacheTTL  time.Duration
+
+	SessionTTL       time.Duration
+	SessionIdle      time.Duration
+	SessionRetention time.Duration
+	JanitorInterval  time.Duration
+	StreamTokenTTL   time.Duration
+
+	GeminiTimeout   time.Duration
+	BreakerFailures int
+	BreakerCooldown time.Duration
+
+	RetryAttempts  int
+	RetryBudget    time.Duration
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+	RetryJitter    float64
+
+	PriceInputPerMTok  float64
+	PriceOutputPerMTok float64
+	SessionTokenBudget int64
+	DailyTokenBudget   int64
+
+	AllowDevSecret bool
 }
 
 func Load() Config {
 	return Config{
 		Port:         getenv("PORT", "8080"),
-		JWTSecret:    getenv("JWT_SECRET", "devsecret"),
+		JWTSecret:    getenv("JWT_SECRET", ""),
 		TTSBase:      getenv("TTS_BASE_URL", ""),
-		GeminiAPIKey: getenv("GEMINI_API_KEY", ""),
+		GeminiAPIKey: getenv("GOOGLE_API_KEY", os.Getenv("GEMINI_API_KEY")),
+		GeminiModel:  getenv("GEMINI_MODEL", "gemini-2.5-flash"),
+		GeminiURL:    getenv("GEMINI_BASE_URL", ""),
+		LiveURL:      getenv("GEMINI_LIVE_URL", ""),
+		LiveModel:    getenv("GEMINI_LIVE_MODEL", ""),
+		LivePrompt:   getenv("GEMINI_LIVE_PROMPT", ""),
+		PromptDir:    getenv("PROMPT_DIR", ""),
+		SessionStore: getenv("SESSION_STORE", "memory"),
+		SessionDir:   getenv("SESSION_DIR", "data/sessions"),
+
+		LiveMaxRetries: getint("GEMINI_LIVE_MAX_RETRIES", 5),
+
+		LocaleFallback: getlist("LOCALE_FALLBACK", "en"),
+		TipHistory:     getint("TIP_HISTORY", 5),
+
+		TipCooldown:    getdur("TIP_COOLDOWN", 10*time.Second),
+		TipSimilarity:  getfloat("TIP_SIMILARITY", 0.8),
+		TipHold:        getdur("TIP_PRIORITY_HOLD", 4*time.Second),
+		TipPraiseEvery: getdur("TIP_PRAISE_EVERY", 15*time.Second),
+
+		CaptureCountdown: getdur("CAPTURE_COUNTDOWN", 3*time.Second),
+		ReadyStableTips:  getint("READY_STABLE_TIPS", 3),
+		ReadyCooldown:    getdur("READY_COOLDOWN", 10*time.Second),
+
+		CaptureMaxBytes:    int64(getint("CAPTURE_MAX_BYTES", 20<<20)),
+		CaptureRateTimeout: getdur("CAPTURE_RATE_TIMEOUT", 10*time.Second),
+
+		FrameTypes:   getlist("FRAME_TYPES", "image/jpeg,image/png,image/webp"),
+		FrameMaxDim:  getint("FRAME_MAX_DIM", 1024),
+		FrameQuality: getint("FRAME_QUALITY", 80),
+
+		MotionThreshold: getint("MOTION_THRESHOLD", 4),
+		MotionMaxReuse:  getdur("MOTION_MAX_REUSE", 10*time.Second),
+
+		TipCacheSize: getint("TIP_CACHE_SIZE", 512),
+		TipCacheTTL:  getdur("TIP_CACHE_TTL", 10*time.Minute),
+
+		SessionTTL:       getdur("SESSION_TTL", 2*time.Hour),
+		SessionIdle:      getdur("SESSION_IDLE_TIMEOUT", 10*time.Minute),
+		SessionRetention: getdur("SESSION_RETENTION", 24*time.Hour),
+		JanitorInterval:  getdur("SESSION_JANITOR_INTERVAL", time.Minute),
+		StreamTokenTTL:   getdur("STREAM_TOKEN_TTL", 2*time.Minute),
+
+		GeminiTimeout:   getdur("GEMINI_TIMEOUT", 1500*time.Millisecond),
+		BreakerFailures: getint("BREAKER_FAILURES", 3),
+		BreakerCooldown: getdur("BREAKER_COOLDOWN", 30*time.Second),
+
+		RetryAttempts:  getint("GEMINI_RETRY_ATTEMPTS", 3),
+		RetryBudget:    getdur("GEMINI_RETRY_BUDGET", 1500*time.Millisecond),
+		RetryBaseDelay: getdur("GEMINI_RETRY_BASE_DELAY", 200*time.Millisecond),
+		RetryMaxDelay:  getdur("GEMINI_RETRY_MAX_DELAY", time.Second),
+		RetryJitter:    getfloat("GEMINI_RETRY_JITTER", 0.2),
+
+		PriceInputPerMTok:  getfloat("GEMINI_PRICE_INPUT_PER_MTOK", 0.30),
+		PriceOutputPerMTok: getfloat("GEMINI_PRICE_OUTPUT_PER_MTOK", 2.50),
+		SessionTokenBudget: int64(getint("SESSION_TOKEN_BUDGET", 0)),
+		DailyTokenBudget:   int64(getint("DAILY_TOKEN_BUDGET", 0)),
+
+		AllowDevSecret: os.Getenv("JWT_ALLOW_DEV_SECRET") == "1",
 	}
 }
 
@@ -24,3 +153,43 @@ func getenv(k, d string) string {
 	}
 	return d
 }
+
+// getlist splits a comma-separated value, trimming spaces and dropping
+// empty entries.
+func getlist(k, d string) []string {
+	if v := splitList(os.Getenv(k)); len(v) > 0 {
+		return v
+	}
+	return splitList(d)
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func getdur(k string, d time.Duration) time.Duration {
+	if v, err := time.ParseDuration(os.Getenv(k)); err == nil {
+		return v
+	}
+	return d
+}
+
+func getint(k string, d int) int {
+	if v, err := strconv.Atoi(os.Getenv(k)); err == nil {
+		return v
+	}
+	return d
+}
+
+func getfloat(k string, d float64) float64 {
+	if v, err := strconv.ParseFloat(os.Getenv(k), 64); err == nil {
+		return v
+	}
+	return d
+}
@@ -0,0 +1,128 @@
+// Package capture scores finished photos on the criteria the tip pipeline
+// coaches towards: exposure, sharpness, a level horizon and, when a model is
+// available, its rating of the shot.
+package capture
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/steveyiyo/hackyou-backend/internal/core/vision"
+	"github.com/steveyiyo/hackyou-backend/pkg/types"
+)
+
+// Rater rates a photo from 0 to 10 with a short comment. The session's
+// mode and locale come from tips.SessionFrom(ctx).
+type Rater interface {
+	RatePhoto(ctx context.Context, img []byte, mime string) (float64, string, error)
+}
+
+// Weights weigh the component scores in Capture.Score. A missing model
+// rating leaves its weight out.
+type Weights struct {
+	Exposure  float64
+	Sharpness float64
+	Level     float64
+	Model     float64
+}
+
+var DefaultWeights = Weights{Exposure: 0.3, Sharpness: 0.3, Level: 0.15, Model: 0.25}
+
+// Reference points for the metric scores, on 320px-wide luma like the tip
+// heuristic's thresholds.
+const (
+	idealBrightness = 128
+	sharpnessScale  = 120
+	tiltScale       = 10
+)
+
+// Scorer scores uploaded photos.
+type Scorer struct {
+	// Rater adds a model rating; nil scores on metrics alone.
+	Rater Rater
+	// Timeout bounds the model rating; zero means no extra deadline.
+	Timeout time.Duration
+	// Weights default to DefaultWeights when zero.
+	Weights Weights
+}
+
+func NewScorer(r Rater) *Scorer { return &Scorer{Rater: r} }
+
+// Score decodes img and scores it. The model is asked only when rate is
+// set, with img downscaled as for a stream frame; its failure leaves the
+// rating out rather than failing the capture.
+// The returned Capture has no ID or T yet.
+func (s *Scorer) Score(ctx context.Context, img []byte, mime string, rate bool) (types.Capture, error) {
+	if _, err := vision.CheckSize(img); err != nil {
+		return types.Capture{}, err
+	}
+	im, mime, err := vision.Decode(img, mime)
+	if err != nil {
+		return types.Capture{}, err
+	}
+	m := vision.Analyze(im)
+	c := types.Capture{
+		ContentType: mime,
+		Width:       m.Width,
+		Height:      m.Height,
+		Bytes:       int64(len(img)),
+		Scores: types.CaptureScores{
+			Exposure:  round3(exposureScore(m)),
+			Sharpness: round3(1 - math.Exp(-m.Sharpness/sharpnessScale)),
+			Level:     round3(1 - m.TiltConfidence*min(math.Abs(m.Tilt)/tiltScale, 1)),
+		},
+	}
+	if rate && s.Rater != nil {
+		if s.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+			defer cancel()
+		}
+		// Full-size photos can exceed the model's inline upload limit.
+		if f, err := vision.PrepareFrame(img, mime, vision.FrameOptions{}); err == nil {
+			if r, comment, err := s.Rater.RatePhoto(ctx, f.Data, f.ContentType); err == nil {
+				c.Scores.Model = &r
+				c.Comment = comment
+			}
+		}
+	}
+	c.Score = s.total(c.Scores)
+	return c, nil
+}
+
+// exposureScore falls off quadratically with the distance of the mean
+// luma from mid-grey and loses the share of clipped pixels.
+func exposureScore(m vision.Metrics) float64 {
+	d := (m.Brightness - idealBrightness) / idealBrightness
+	return clamp01(1 - d*d - 2*m.Highlights - m.Shadows)
+}
+
+func (s *Scorer) total(sc types.CaptureScores) float64 {
+	w := s.Weights
+	if w == (Weights{}) {
+		w = DefaultWeights
+	}
+	sum := w.Exposure*sc.Exposure + w.Sharpness*sc.Sharpness + w.Level*sc.Level
+	n := w.Exposure + w.Sharpness + w.Level
+	if sc.Model != nil {
+		sum += w.Model * *sc.Model / 10
+		n += w.Model
+	}
+	if n == 0 {
+		return 0
+	}
+	return math.Round(1000*sum/n) / 10
+}
+
+func clamp01(v float64) float64 { return min(max(v, 0), 1) }
+
+func round3(v float64) float64 { return math.Round(v*1000) / 1000 }
+
+// Rank sorts captures best first; equal scores keep upload order.
+func Rank(cs []types.Capture) []types.Capture {
+	out := append([]types.Capture(nil), cs...)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	return out
+}
@@ -0,0 +1,88 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/steveyiyo/hackyou-backend/internal/core/vision"
+	"github.com/steveyiyo/hackyou-backend/pkg/types"
+)
+
+type fakeRater struct {
+	mime string
+	cfg  image.Config
+}
+
+func (r *fakeRater) RatePhoto(_ context.Context, img []byte, mime string) (float64, string, error) {
+	r.mime = mime
+	r.cfg, _, _ = image.DecodeConfig(bytes.NewReader(img))
+	return 8, "nice", nil
+}
+
+func photo(t *testing.T, w, h int) []byte {
+	t.Helper()
+	im := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			im.SetGray(x, y, color.Gray{uint8((x*7 + y*13) % 256)})
+		}
+	}
+	var b bytes.Buffer
+	if err := png.Encode(&b, im); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestScoreDownscalesForRater(t *testing.T) {
+	r := &fakeRater{}
+	img := photo(t, 3000, 2000)
+	c, err := NewScorer(r).Score(context.Background(), img, "image/png", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Width != 3000 || c.Height != 2000 || c.Bytes != int64(len(img)) || c.ContentType != "image/png" {
+		t.Errorf("capture describes %dx%d %s of %d bytes, want the upload", c.Width, c.Height, c.ContentType, c.Bytes)
+	}
+	if dim := vision.DefaultFrameOptions.MaxDim; r.mime != "image/jpeg" || r.cfg.Width != dim || r.cfg.Height != dim*2/3 {
+		t.Errorf("rater got %dx%d %s, want %dx%d image/jpeg", r.cfg.Width, r.cfg.Height, r.mime, dim, dim*2/3)
+	}
+	if c.Scores.Model == nil || *c.Scores.Model != 8 || c.Comment != "nice" {
+		t.Errorf("model rating %v %q not kept", c.Scores.Model, c.Comment)
+	}
+}
+
+func TestScoreWithoutRating(t *testing.T) {
+	r := &fakeRater{}
+	c, err := NewScorer(r).Score(context.Background(), photo(t, 64, 48), "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.mime != "" || c.Scores.Model != nil {
+		t.Error("rater asked although rate was false")
+	}
+}
+
+func TestTotal(t *testing.T) {
+	s := &Scorer{}
+	eight := 8.0
+	tests := []struct {
+		sc   types.CaptureScores
+		want float64
+	}{
+		{types.CaptureScores{Exposure: 1, Sharpness: 1, Level: 1}, 100},
+		{types.CaptureScores{Exposure: 0.5, Sharpness: 0.5, Level: 0.5}, 50},
+		// 0.3 + 0.25*0.8 over weights 0.3+0.3+0.15+0.25.
+		{types.CaptureScores{Exposure: 1, Model: &eight}, 50},
+		{types.CaptureScores{Level: 1}, 20},
+	}
+	for _, tt := range tests {
+		if got := s.total(tt.sc); got != tt.want {
+			t.Errorf("total(%+v) = %v, want %v", tt.sc, got, tt.want)
+		}
+	}
+}
@@ -5,26 +5,35 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
-	"errors"
-	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/natefinch/lumberjack"
 	"google.golang.org/genai"
 
+	"github.com/steveyiyo/hackyou-backend/internal/core/prompts"
+	"github.com/steveyiyo/hackyou-backend/internal/core/tips"
+	"github.com/steveyiyo/hackyou-backend/internal/core/usage"
 	"github.com/steveyiyo/hackyou-backend/pkg/types"
 )
 
 type Client struct {
 	c     *genai.Client
 	model string
+	// Retry applies to every call; zero fields take DefaultRetryPolicy.
+	Retry RetryPolicy
+	// Prompts picks the prompt by session mode and locale; nil uses the
+	// embedded prompts.
+	Prompts *prompts.Registry
 }
 
-func New(apiKey, model string) (*Client, error) {
+// New creates a client for model. baseURL overrides the API endpoint, e.g.
+// to point at a geminitest server; empty uses Google's.
+func New(apiKey, model, baseURL string) (*Client, error) {
 	tr := &http.Transport{
 		Proxy:             http.ProxyFromEnvironment,
 		TLSClientConfig:   &tls.Config{MinVersion: tls.VersionTLS12},
@@ -37,6 +46,7 @@ func New(apiKey, model string) (*Client, error) {
 		_ = os.MkdirAll("logs", 0755)
 		rt = &dumpTransport{base: tr, w: &lumberjack.Logger{Filename: "logs/gemini-http.log", MaxSize: 50, MaxBackups: 3, MaxAge: 7, Compress: true}}
 	}
+	rt = &retryAfterTransport{base: rt}
 	hc := &http.Client{Transport: rt, Timeout: 30 * time.Second}
 	reqTimeout := 15 * time.Second
 	cl, err := genai.NewClient(context.Background(), &genai.ClientConfig{
@@ -44,6 +54,7 @@ func New(apiKey, model string) (*Client, error) {
 		Backend:    genai.BackendGeminiAPI,
 		HTTPClient: hc,
 		HTTPOptions: genai.HTTPOptions{
+			BaseURL:    baseURL,
 			APIVersion: "v1beta",
 			Timeout:    &reqTimeout,
 		},
@@ -56,9 +67,30 @@ func New(apiKey, model string) (*Client, error) {
 
 func (g *Client) Close() error { return nil }
 
+var priorities = []string{types.PriorityCritical, types.PriorityHigh, types.PriorityNormal, types.PriorityPraise}
+
+var defaultPrompts = sync.OnceValue(prompts.Default)
+
+func (g *Client) prompts() *prompts.Registry {
+	if g.Prompts != nil {
+		return g.Prompts
+	}
+	return defaultPrompts()
+}
+
+// PromptVersion is the version of the prompt used for mode and locale.
+func (g *Client) PromptVersion(mode, locale string) string {
+	return g.prompts().Lookup(mode, locale).Version
+}
+
 func (g *Client) TipFromImage(ctx context.Context, img []byte, mime string) (*types.Tip, string, error) {
+	sess := tips.SessionFrom(ctx)
+	prompt, _, err := g.prompts().Render(sess.Mode, sess.Locale, promptHistory(sess.History))
+	if err != nil {
+		return nil, "", err
+	}
 	parts := []*genai.Part{
-		{Text: "你現在是專業的攝影教練，假設這個被拍者不太會比姿勢擺表情，你要適當的給他姿勢的指引，包括但不限於「撩一下頭髮」，「雙手叉腰」，「左手扶手肘」，「右手撐臉」。另外要提升他的自信，你要適當的給他讚美，包括但不限於「這樣笑很好看」「Awesome」「Slay」「You look perfect」。所有輸出都必須為 JSON，格式: {\"text\":\"string\",\"yaw_deg\":\"number\",\"pitch_deg\":\"number\",\"roll_deg\":\"number\"}，角度欄位可省略，text 要短且可操作。所有內容用英文回傳。如果你認為使用者的角度很棒，就回傳「Ready!」"},
+		{Text: prompt},
 		{InlineData: &genai.Blob{Data: img, MIMEType: mime}},
 	}
 	temp := float32(0.2)
@@ -69,10 +101,13 @@ func (g *Client) TipFromImage(ctx context.Context, img []byte, mime string) (*ty
 		ResponseSchema: &genai.Schema{
 			Type: genai.TypeObject,
 			Properties: map[string]*genai.Schema{
-				"text":      {Type: genai.TypeString},
-				"yaw_deg":   {Type: genai.TypeNumber},
-				"pitch_deg": {Type: genai.TypeNumber},
-				"roll_deg":  {Type: genai.TypeNumber},
+				"text":              {Type: genai.TypeString},
+				"priority":          {Type: genai.TypeString, Enum: priorities},
+				"yaw_deg":           {Type: genai.TypeNumber},
+				"pitch_deg":         {Type: genai.TypeNumber},
+				"roll_deg":          {Type: genai.TypeNumber},
+				"previous_followed": {Type: genai.TypeBoolean},
+				"ready":             {Type: genai.TypeBoolean},
 			},
 			Required: []string{"text"},
 		},
@@ -85,87 +120,158 @@ func (g *Client) TipFromImage(ctx context.Context, img []byte, mime string) (*ty
 		TopP:            &topP,
 		MaxOutputTokens: maxTok,
 	}
-	tip, raw, err := g.callOnce(ctx, parts, cfgJSON)
-	// debug
-	fmt.Printf("Raw: %s\n", raw)
-	if err != nil && (schemaUnsupported(err) || invalidGenerationConfig(err)) {
-		return g.callOnce(ctx, parts, cfgText)
+	var tip *types.Tip
+	raw, err := g.generate(ctx, g.Retry.withDefaults().Budget, parts, cfgJSON, cfgText, func(resp *genai.GenerateContentResponse) (string, bool) {
+		t, raw, ok := parseTip(resp)
+		tip = t
+		return raw, ok
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	finalize(tip)
+	return tip, raw, nil
+}
+
+// RatePhoto scores a finished photo from 0 to 10 for the session's mode,
+// with a short comment in its locale.
+func (g *Client) RatePhoto(ctx context.Context, img []byte, mime string) (float64, string, error) {
+	sess := tips.SessionFrom(ctx)
+	prompt, _, err := g.prompts().RenderRating(sess.Mode, sess.Locale)
+	if err != nil {
+		return 0, "", err
+	}
+	parts := []*genai.Part{
+		{Text: prompt},
+		{InlineData: &genai.Blob{Data: img, MIMEType: mime}},
 	}
-	if tip != nil && err == nil {
-		return tip, raw, nil
+	temp := float32(0)
+	maxTok := int32(1024)
+	cfgJSON := &genai.GenerateContentConfig{
+		ResponseMIMEType: "application/json",
+		ResponseSchema: &genai.Schema{
+			Type: genai.TypeObject,
+			Properties: map[string]*genai.Schema{
+				"score":   {Type: genai.TypeNumber},
+				"comment": {Type: genai.TypeString},
+			},
+			Required: []string{"score"},
+		},
+		Temperature:     &temp,
+		MaxOutputTokens: maxTok,
+	}
+	cfgText := &genai.GenerateContentConfig{
+		Temperature:     &temp,
+		MaxOutputTokens: maxTok,
+	}
+	var out struct {
+		Score   *float64 `json:"score"`
+		Comment string   `json:"comment"`
+	}
+	// Rating a full-size photo is not bound to the tip loop's budget; the
+	// caller's deadline limits it instead.
+	_, err = g.generate(ctx, 0, parts, cfgJSON, cfgText, func(resp *genai.GenerateContentResponse) (string, bool) {
+		t := unfence(resp.Text())
+		return t, json.Unmarshal([]byte(t), &out) == nil && out.Score != nil
+	})
+	if err != nil {
+		return 0, "", err
 	}
-	return g.callOnce(ctx, parts, cfgText)
+	return min(max(*out.Score, 0), 10), out.Comment, nil
 }
 
-func (g *Client) callOnce(ctx context.Context, parts []*genai.Part, cfg *genai.GenerateContentConfig) (*types.Tip, string, error) {
-	var lastErr error
-	mt := cfg.MaxOutputTokens
-	for i := 0; i < 3; i++ {
-		resp, err := g.c.Models.GenerateContent(ctx, g.model, []*genai.Content{{Parts: parts}}, cfg)
-		if err != nil {
-			lastErr = err
-			fmt.Printf("Error: %v\n", err)
-			if retriable(err) {
-				time.Sleep(time.Duration(300*(i+1)) * time.Millisecond)
-				continue
-			}
-			return nil, "", err
-		}
-		if tip, raw, ok := parseTip(resp); ok {
-			finalize(tip)
-			return tip, raw, nil
-		}
-		fr := ""
-		if len(resp.Candidates) > 0 {
-			fr = string(resp.Candidates[0].FinishReason)
-		}
-		if fr == "MAX_TOKENS" && cfg.MaxOutputTokens < mt+256 {
-			cfg.MaxOutputTokens += 256
-			time.Sleep(time.Duration(300*(i+1)) * time.Millisecond)
-			continue
+// unfence strips the Markdown code fence models sometimes wrap JSON in
+// when no response schema applies.
+func unfence(t string) string {
+	t = strings.TrimSpace(t)
+	t = strings.TrimPrefix(t, "```json")
+	t = strings.TrimPrefix(t, "```")
+	return strings.TrimSpace(strings.TrimSuffix(t, "```"))
+}
+
+func promptHistory(h []tips.Turn) []prompts.Turn {
+	out := make([]prompts.Turn, len(h))
+	for i, t := range h {
+		f := "unknown"
+		switch {
+		case t.Followed == nil:
+		case *t.Followed:
+			f = "yes"
+		default:
+			f = "no"
 		}
-		lastErr = errors.New("empty response")
-		time.Sleep(time.Duration(300*(i+1)) * time.Millisecond)
+		out[i] = prompts.Turn{Text: t.Text, AgoSec: int64(t.Age.Seconds()), Followed: f}
 	}
-	return nil, "", lastErr
+	return out
 }
 
-func DumpResp(resp *genai.GenerateContentResponse) {
-	if resp == nil {
-		return
+// generate asks the model with cfg until parse accepts a response,
+// retrying per g.Retry within budget, if positive, and returns the raw
+// answer parse reported. Once the model rejects cfg or answers it with
+// nothing usable, later attempts use fallback instead.
+func (g *Client) generate(ctx context.Context, budget time.Duration, parts []*genai.Part, cfg, fallback *genai.GenerateContentConfig, parse func(*genai.GenerateContentResponse) (string, bool)) (string, error) {
+	p := g.Retry.withDefaults()
+	if budget > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, budget)
+		defer cancel()
 	}
-	fmt.Printf("model=%s id=%s\n", resp.ModelVersion, resp.ResponseID)
-	var b strings.Builder
-	for _, c := range resp.Candidates {
-		if c == nil || c.Content == nil {
-			continue
-		}
-		fmt.Printf("finish=%s\n", c.FinishReason)
-		for _, p := range c.Content.Parts {
-			if p.Text != "" {
-				b.WriteString(p.Text)
+
+	var lastErr error
+	mt := cfg.MaxOutputTokens
+	for n := 1; ; n++ {
+		actx, hint := withRetryHint(ctx)
+		resp, err := g.c.Models.GenerateContent(actx, g.model, []*genai.Content{{Parts: parts}}, cfg)
+		var e *Error
+		if err != nil {
+			e = classify(err)
+			if e.RetryAfter == 0 {
+				e.RetryAfter = hint.get()
+			}
+			if e.ConfigRejected() && cfg != fallback {
+				cfg = fallback
+				lastErr = e
+				if n >= p.MaxAttempts {
+					break
+				}
+				continue
+			}
+			if !e.Retriable() {
+				return "", e
+			}
+		} else {
+			if u := resp.UsageMetadata; u != nil {
+				usage.Record(ctx, g.model, usage.Tokens{
+					Prompt: int64(u.PromptTokenCount),
+					Output: int64(u.CandidatesTokenCount + u.ThoughtsTokenCount),
+					Total:  int64(u.TotalTokenCount),
+				})
+			}
+			if raw, ok := parse(resp); ok {
+				return raw, nil
+			}
+			e = responseError(resp)
+			if e.Kind == KindSafetyBlocked {
+				return "", e
 			}
-			if p.InlineData != nil && p.InlineData.MIMEType == "application/json" {
-				fmt.Println("json:", string(p.InlineData.Data))
+			truncated := len(resp.Candidates) > 0 && resp.Candidates[0].FinishReason == genai.FinishReasonMaxTokens
+			if truncated && cfg.MaxOutputTokens < mt+256 {
+				cfg.MaxOutputTokens += 256
+			} else {
+				cfg = fallback
 			}
 		}
+		lastErr = e
+		if n >= p.MaxAttempts || !wait(ctx, p.delay(n, e)) {
+			break
+		}
 	}
-	if u := resp.UsageMetadata; u != nil {
-		out := u.CandidatesTokenCount
-		fmt.Printf("tokens prompt=%d output=%d total=%d\n", u.PromptTokenCount, out, u.TotalTokenCount)
-	}
-	txt := strings.TrimSpace(b.String())
-	if txt != "" {
-		fmt.Println("text:", txt)
-	}
+	return "", lastErr
 }
 
 func parseTip(resp *genai.GenerateContentResponse) (*types.Tip, string, bool) {
 	var out types.Tip
 	var raw string
-	// debug
-	DumpResp(resp)
-
 	for _, cand := range resp.Candidates {
 		if cand.Content != nil {
 			for _, p := range cand.Content.Parts {
@@ -177,7 +283,6 @@ func parseTip(resp *genai.GenerateContentResponse) (*types.Tip, string, bool) {
 				}
 				if p.Text != "" {
 					raw = p.Text
-					fmt.Printf("!!!!! %s", raw)
 					var tmp types.Tip
 					if json.Unmarshal([]byte(p.Text), &tmp) == nil && tmp.Text != "" {
 						return &tmp, raw, true
@@ -188,9 +293,7 @@ func parseTip(resp *genai.GenerateContentResponse) (*types.Tip, string, bool) {
 	}
 	if t := resp.Text(); t != "" {
 		raw = t
-		// debug
-		fmt.Println(raw)
-		out = types.Tip{T: time.Now().UnixMilli(), Text: t, Priority: "high", Reason: "gemini"}
+		out = types.Tip{T: time.Now().UnixMilli(), Text: t, Priority: types.PriorityHigh, Reason: "gemini"}
 		return &out, raw, true
 	}
 	return nil, "", false
@@ -200,43 +303,18 @@ func finalize(t *types.Tip) {
 	if t.T == 0 {
 		t.T = time.Now().UnixMilli()
 	}
-	if t.Priority == "" {
-		t.Priority = "high"
+	switch t.Priority {
+	case types.PriorityCritical, types.PriorityHigh, types.PriorityNormal, types.PriorityPraise:
+	default:
+		t.Priority = types.PriorityHigh
 	}
 	if t.Reason == "" {
 		t.Reason = "gemini"
 	}
-}
-
-func retriable(err error) bool {
-	if err == nil {
-		return false
-	}
-	s := err.Error()
-	return strings.Contains(s, "unexpected EOF") ||
-		strings.Contains(s, "timeout") ||
-		strings.Contains(s, "RST_STREAM") ||
-		strings.Contains(s, "connection reset") ||
-		strings.Contains(s, "UNAVAILABLE") ||
-		strings.Contains(s, "internal error")
-}
-
-func schemaUnsupported(err error) bool {
-	if err == nil {
-		return false
-	}
-	s := err.Error()
-	return strings.Contains(s, "Unknown name \"responseMimeType\"") ||
-		strings.Contains(s, "Unknown name \"responseSchema\"") ||
-		strings.Contains(s, "Cannot find field") ||
-		strings.Contains(s, "INVALID_ARGUMENT")
-}
-
-func invalidGenerationConfig(err error) bool {
-	if err == nil {
-		return false
+	// The plain-text fallback has no flag, only the agreed answer.
+	if strings.TrimSpace(t.Text) == prompts.Ready {
+		t.Ready = true
 	}
-	return strings.Contains(err.Error(), "generation_config")
 }
 
 type dumpTransport struct {
@@ -0,0 +1,142 @@
+package gemini_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/steveyiyo/hackyou-backend/internal/core/gemini"
+	"github.com/steveyiyo/hackyou-backend/internal/core/gemini/geminitest"
+	"github.com/steveyiyo/hackyou-backend/internal/core/tips"
+	"github.com/steveyiyo/hackyou-backend/internal/core/usage"
+)
+
+func testFrame(t *testing.T) []byte {
+	t.Helper()
+	im := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for y := 0; y < 48; y++ {
+		for x := 0; x < 64; x++ {
+			im.Set(x, y, color.RGBA{uint8(x * 4), uint8(y * 5), 128, 255})
+		}
+	}
+	var b bytes.Buffer
+	if err := png.Encode(&b, im); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func newEngine(t *testing.T, srv *geminitest.Server) *tips.Engine {
+	t.Helper()
+	gc, err := gemini.New("test-key", "gemini-test", srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gc.Retry = gemini.RetryPolicy{MaxAttempts: 3, Budget: time.Second, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+	return tips.New(
+		tips.Step{Name: "gemini", Provider: gc, Timeout: 2 * time.Second, Failures: 3, Cooldown: time.Minute, Metered: true},
+		tips.Step{Name: tips.SourceHeuristic, Provider: &tips.Heuristic{}, Timeout: time.Second, Local: true},
+	)
+}
+
+func TestEngineWithGemini(t *testing.T) {
+	srv := geminitest.NewServer()
+	defer srv.Close()
+	srv.Enqueue(geminitest.Response{
+		Tip:              geminitest.Tip{Text: "Tilt the phone down a bit.", Pitch: -4},
+		PromptTokens:     100,
+		CandidatesTokens: 20,
+	})
+	e := newEngine(t, srv)
+
+	var got []usage.Tokens
+	ctx := usage.WithRecorder(context.Background(), func(model string, tk usage.Tokens) {
+		got = append(got, tk)
+	})
+	ctx = tips.WithSession(ctx, tips.Session{Mode: "portrait", Locale: "en"})
+	d := e.Decide(ctx, testFrame(t), "image/png")
+
+	if d.Err != nil {
+		t.Fatalf("Err = %v", d.Err)
+	}
+	if d.Source != "gemini" || d.Tip.Text != "Tilt the phone down a bit." || d.Tip.Pitch != -4 {
+		t.Errorf("Decide = %s %+v", d.Source, *d.Tip)
+	}
+	if n := len(srv.Requests()); n != 1 {
+		t.Errorf("requests = %d, want 1", n)
+	}
+	if len(got) != 1 || got[0].Prompt != 100 || got[0].Output != 20 || got[0].Total != 120 {
+		t.Errorf("recorded usage = %+v", got)
+	}
+}
+
+func TestEngineRetriesGemini(t *testing.T) {
+	srv := geminitest.NewServer()
+	defer srv.Close()
+	srv.Enqueue(
+		geminitest.Response{Status: http.StatusServiceUnavailable, ErrStatus: "UNAVAILABLE"},
+		geminitest.Response{Tip: geminitest.Tip{Text: "Step back."}},
+	)
+	d := newEngine(t, srv).Decide(context.Background(), testFrame(t), "image/png")
+
+	if d.Err != nil || d.Source != "gemini" || d.Tip.Text != "Step back." {
+		t.Fatalf("Decide = %s %v %+v", d.Source, d.Err, d.Tip)
+	}
+	if n := len(srv.Requests()); n != 2 {
+		t.Errorf("requests = %d, want 2", n)
+	}
+}
+
+func TestEngineFallsBackToHeuristic(t *testing.T) {
+	srv := geminitest.NewServer()
+	defer srv.Close()
+	srv.SetDefault(geminitest.Response{Status: http.StatusServiceUnavailable, ErrStatus: "UNAVAILABLE"})
+	d := newEngine(t, srv).Decide(context.Background(), testFrame(t), "image/png")
+
+	if d.Source != tips.SourceHeuristic || d.Tip == nil || d.Tip.Text == "" {
+		t.Fatalf("Decide = %s %+v", d.Source, d.Tip)
+	}
+	if k := gemini.KindOf(d.Err); k != gemini.KindTransient {
+		t.Errorf("Err = %v, kind %s, want %s", d.Err, k, gemini.KindTransient)
+	}
+	if n := len(srv.Requests()); n != 3 {
+		t.Errorf("requests = %d, want 3", n)
+	}
+}
+
+func TestLiveClientWithFakeServer(t *testing.T) {
+	srv := geminitest.NewServer()
+	defer srv.Close()
+	srv.SetLive(geminitest.Live{Advice: []string{"Move left.", "Hold still."}})
+	c := gemini.NewLiveClient(gemini.LiveConfig{URL: srv.LiveURL(), APIKey: "test-key"})
+	if err := c.StartStreamingSession(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	advice, err := c.ReceiveAdvice()
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame := testFrame(t)
+	for _, want := range []string{"Move left.", "Hold still.", "Move left."} {
+		if err := c.SendImageFrame(frame, "image/png"); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case got := <-advice:
+			if got != want {
+				t.Errorf("advice = %q, want %q", got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no advice for %q", want)
+		}
+	}
+	if !c.Connected() {
+		t.Error("not connected")
+	}
+}
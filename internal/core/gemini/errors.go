@@ -0,0 +1,233 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// Kind classifies why a Gemini call failed.
+type Kind string
+
+const (
+	KindUnknown        Kind = "unknown"
+	KindRateLimited    Kind = "rate_limited"
+	KindQuotaExhausted Kind = "quota_exhausted"
+	KindSafetyBlocked  Kind = "safety_blocked"
+	KindBadRequest     Kind = "bad_request"
+	KindUnauthorized   Kind = "unauthorized"
+	KindTransient      Kind = "transient"
+	KindDeadline       Kind = "deadline"
+	KindCanceled       Kind = "canceled"
+	KindEmpty          Kind = "empty_response"
+)
+
+// Error is a classified Gemini failure. Status is the HTTP status and Code
+// the API status (e.g. "RESOURCE_EXHAUSTED") when the server answered.
+// RetryAfter is the server's requested wait before retrying, if any.
+// Fields are the request fields a bad request was rejected for, from its
+// google.rpc.BadRequest detail.
+type Error struct {
+	Kind       Kind
+	Status     int
+	Code       string
+	Message    string
+	RetryAfter time.Duration
+	Fields     []string
+	Err        error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return "gemini " + string(e.Kind) + ": " + e.Err.Error()
+	}
+	return "gemini " + string(e.Kind) + ": " + e.Message
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Retriable reports whether the same request may succeed if repeated.
+func (e *Error) Retriable() bool {
+	switch e.Kind {
+	case KindRateLimited, KindTransient, KindEmpty:
+		return true
+	}
+	return false
+}
+
+// ConfigRejected reports whether the request was refused for a field of
+// its generation config, such as a structured-output setting the model
+// does not accept, in which case a plain-text request may still work.
+func (e *Error) ConfigRejected() bool {
+	if e.Kind != KindBadRequest {
+		return false
+	}
+	for _, f := range e.Fields {
+		if hasField(f, "generation_config") || hasField(f, "generationConfig") {
+			return true
+		}
+	}
+	return false
+}
+
+// hasField reports whether path is field or one of its subfields.
+func hasField(path, field string) bool {
+	return path == field || strings.HasPrefix(path, field+".")
+}
+
+// KindOf returns the Kind of err, or KindUnknown if it is not a Gemini
+// error.
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return KindUnknown
+}
+
+// classify wraps err from the genai SDK or the HTTP transport in an *Error.
+func classify(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	var api genai.APIError
+	if errors.As(err, &api) {
+		return &Error{Kind: apiKind(api), Status: api.Code, Code: api.Status, Message: api.Message, RetryAfter: retryDelay(api), Fields: badFields(api), Err: err}
+	}
+	out := &Error{Kind: KindUnknown, Message: err.Error(), Err: err}
+	var ne net.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		out.Kind = KindCanceled
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &ne) && ne.Timeout():
+		out.Kind = KindDeadline
+	case errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF),
+		errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.EPIPE), errors.As(err, new(*net.OpError)):
+		out.Kind = KindTransient
+	}
+	return out
+}
+
+func apiKind(api genai.APIError) Kind {
+	switch {
+	case api.Code == http.StatusTooManyRequests || api.Status == "RESOURCE_EXHAUSTED":
+		if isQuota(api) {
+			return KindQuotaExhausted
+		}
+		return KindRateLimited
+	case api.Code == http.StatusUnauthorized || api.Code == http.StatusForbidden ||
+		api.Status == "UNAUTHENTICATED" || api.Status == "PERMISSION_DENIED":
+		return KindUnauthorized
+	case api.Code == http.StatusGatewayTimeout || api.Status == "DEADLINE_EXCEEDED":
+		return KindDeadline
+	case api.Code >= 500:
+		return KindTransient
+	case api.Code >= 400:
+		return KindBadRequest
+	}
+	return KindUnknown
+}
+
+// Error detail types, without their type.googleapis.com/ prefix.
+const (
+	detailBadRequest   = "google.rpc.BadRequest"
+	detailQuotaFailure = "google.rpc.QuotaFailure"
+	detailRetryInfo    = "google.rpc.RetryInfo"
+)
+
+func detailType(d map[string]interface{}) string {
+	t, _ := d["@type"].(string)
+	return t[strings.LastIndex(t, "/")+1:]
+}
+
+// detailList returns the objects in list field key of detail d.
+func detailList(d map[string]interface{}, key string) []map[string]interface{} {
+	l, _ := d[key].([]interface{})
+	out := make([]map[string]interface{}, 0, len(l))
+	for _, v := range l {
+		if m, ok := v.(map[string]interface{}); ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// isQuota tells a daily or project quota apart from a short-term rate
+// limit by the QuotaFailure detail: it is exhausted when a per-day quota
+// was violated, or when the server gives no RetryInfo to wait for.
+func isQuota(api genai.APIError) bool {
+	var quota, retry bool
+	for _, d := range api.Details {
+		switch detailType(d) {
+		case detailQuotaFailure:
+			quota = true
+			for _, v := range detailList(d, "violations") {
+				if id, _ := v["quotaId"].(string); strings.Contains(id, "PerDay") {
+					return true
+				}
+			}
+		case detailRetryInfo:
+			retry = true
+		}
+	}
+	return quota && !retry
+}
+
+// badFields lists the fields of the google.rpc.BadRequest detail's field
+// violations.
+func badFields(api genai.APIError) []string {
+	var out []string
+	for _, d := range api.Details {
+		if detailType(d) != detailBadRequest {
+			continue
+		}
+		for _, v := range detailList(d, "fieldViolations") {
+			if f, _ := v["field"].(string); f != "" {
+				out = append(out, f)
+			}
+		}
+	}
+	return out
+}
+
+// retryDelay reads the google.rpc.RetryInfo detail, e.g. "retryDelay": "27s".
+func retryDelay(api genai.APIError) time.Duration {
+	for _, d := range api.Details {
+		if detailType(d) != detailRetryInfo {
+			continue
+		}
+		if v, _ := d["retryDelay"].(string); v != "" {
+			if dur, err := time.ParseDuration(v); err == nil {
+				return dur
+			}
+		}
+	}
+	return 0
+}
+
+// responseError classifies a response that produced no usable tip.
+func responseError(resp *genai.GenerateContentResponse) *Error {
+	if pf := resp.PromptFeedback; pf != nil && pf.BlockReason != "" {
+		return &Error{Kind: KindSafetyBlocked, Code: string(pf.BlockReason), Message: pf.BlockReasonMessage}
+	}
+	for _, c := range resp.Candidates {
+		switch c.FinishReason {
+		case genai.FinishReasonSafety, genai.FinishReasonProhibitedContent, genai.FinishReasonBlocklist,
+			genai.FinishReasonSPII, genai.FinishReasonImageSafety:
+			return &Error{Kind: KindSafetyBlocked, Code: string(c.FinishReason), Message: "response blocked"}
+		}
+	}
+	return &Error{Kind: KindEmpty, Message: "empty response"}
+}
@@ -0,0 +1,139 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+func quotaFailure(quotaID string) map[string]interface{} {
+	return map[string]interface{}{
+		"@type": "type.googleapis.com/google.rpc.QuotaFailure",
+		"violations": []interface{}{
+			map[string]interface{}{"quotaMetric": "generativelanguage.googleapis.com/generate_content_requests", "quotaId": quotaID},
+		},
+	}
+}
+
+func retryInfo(d string) map[string]interface{} {
+	return map[string]interface{}{"@type": "type.googleapis.com/google.rpc.RetryInfo", "retryDelay": d}
+}
+
+func badRequest(fields ...string) map[string]interface{} {
+	var vs []interface{}
+	for _, f := range fields {
+		vs = append(vs, map[string]interface{}{"field": f, "description": "Invalid JSON payload received."})
+	}
+	return map[string]interface{}{"@type": "type.googleapis.com/google.rpc.BadRequest", "fieldViolations": vs}
+}
+
+func apiErr(code int, status, msg string, details ...map[string]interface{}) error {
+	return fmt.Errorf("generate: %w", genai.APIError{Code: code, Status: status, Message: msg, Details: details})
+}
+
+func TestClassify(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+	tests := []struct {
+		name       string
+		err        error
+		kind       Kind
+		retriable  bool
+		retryAfter time.Duration
+	}{
+		{"rate limit", apiErr(429, "RESOURCE_EXHAUSTED", "Resource has been exhausted (e.g. check quota).",
+			quotaFailure("GenerateRequestsPerMinutePerProjectPerModel"), retryInfo("27s")), KindRateLimited, true, 27 * time.Second},
+		{"daily quota", apiErr(429, "RESOURCE_EXHAUSTED", "", quotaFailure("GenerateRequestsPerDayPerProjectPerModel"), retryInfo("3s")),
+			KindQuotaExhausted, false, 3 * time.Second},
+		{"quota without retry", apiErr(429, "RESOURCE_EXHAUSTED", "", quotaFailure("")), KindQuotaExhausted, false, 0},
+		{"quota only in message", apiErr(429, "RESOURCE_EXHAUSTED", "You exceeded your current quota."), KindRateLimited, true, 0},
+		{"status only", apiErr(0, "RESOURCE_EXHAUSTED", ""), KindRateLimited, true, 0},
+		{"unauthenticated", apiErr(401, "UNAUTHENTICATED", ""), KindUnauthorized, false, 0},
+		{"permission denied", apiErr(403, "PERMISSION_DENIED", ""), KindUnauthorized, false, 0},
+		{"gateway timeout", apiErr(504, "DEADLINE_EXCEEDED", ""), KindDeadline, false, 0},
+		{"unavailable", apiErr(503, "UNAVAILABLE", ""), KindTransient, true, 0},
+		{"internal", apiErr(500, "INTERNAL", ""), KindTransient, true, 0},
+		{"invalid argument", apiErr(400, "INVALID_ARGUMENT", ""), KindBadRequest, false, 0},
+		{"canceled", fmt.Errorf("post: %w", context.Canceled), KindCanceled, false, 0},
+		{"deadline", fmt.Errorf("post: %w", context.DeadlineExceeded), KindDeadline, false, 0},
+		{"eof", fmt.Errorf("read: %w", io.ErrUnexpectedEOF), KindTransient, true, 0},
+		{"connection refused", refused, KindTransient, true, 0},
+		{"other", errors.New("boom"), KindUnknown, false, 0},
+	}
+	for _, tt := range tests {
+		e := classify(tt.err)
+		if e.Kind != tt.kind || e.Retriable() != tt.retriable || e.RetryAfter != tt.retryAfter {
+			t.Errorf("%s: kind %s, retriable %v, retry after %v; want %s, %v, %v",
+				tt.name, e.Kind, e.Retriable(), e.RetryAfter, tt.kind, tt.retriable, tt.retryAfter)
+		}
+		if !errors.Is(e, tt.err) {
+			t.Errorf("%s: classified error does not wrap the original", tt.name)
+		}
+		if KindOf(fmt.Errorf("tip: %w", e)) != tt.kind {
+			t.Errorf("%s: KindOf = %s", tt.name, KindOf(e))
+		}
+	}
+	if classify(nil) != nil {
+		t.Error("classify(nil) != nil")
+	}
+	e := &Error{Kind: KindEmpty}
+	if classify(fmt.Errorf("wrapped: %w", e)) != e {
+		t.Error("classify did not return an existing *Error")
+	}
+}
+
+func TestConfigRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		fields []string
+		want   bool
+	}{
+		{"unknown schema field", apiErr(400, "INVALID_ARGUMENT", `Invalid JSON payload received. Unknown name "responseSchema" at 'generation_config'`,
+			badRequest("generation_config")), []string{"generation_config"}, true},
+		{"schema subfield", apiErr(400, "INVALID_ARGUMENT", "", badRequest("generationConfig.responseSchema.properties[text]")),
+			[]string{"generationConfig.responseSchema.properties[text]"}, true},
+		{"other field", apiErr(400, "INVALID_ARGUMENT", "", badRequest("contents[0].parts[1].inline_data")),
+			[]string{"contents[0].parts[1].inline_data"}, false},
+		{"similar name", apiErr(400, "INVALID_ARGUMENT", "", badRequest("generation_config_extra")), []string{"generation_config_extra"}, false},
+		{"message only", apiErr(400, "INVALID_ARGUMENT", "responseSchema is not supported by generation_config"), nil, false},
+		{"not a bad request", apiErr(500, "INTERNAL", "", badRequest("generation_config")), []string{"generation_config"}, false},
+	}
+	for _, tt := range tests {
+		e := classify(tt.err)
+		if !slices.Equal(e.Fields, tt.fields) {
+			t.Errorf("%s: fields %q, want %q", tt.name, e.Fields, tt.fields)
+		}
+		if got := e.ConfigRejected(); got != tt.want {
+			t.Errorf("%s: ConfigRejected = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIsQuota(t *testing.T) {
+	tests := []struct {
+		name    string
+		details []map[string]interface{}
+		want    bool
+	}{
+		{"none", nil, false},
+		{"per minute with retry", []map[string]interface{}{quotaFailure("GenerateRequestsPerMinutePerProjectPerModel-FreeTier"), retryInfo("10s")}, false},
+		{"per day with retry", []map[string]interface{}{quotaFailure("GenerateRequestsPerDayPerProjectPerModel-FreeTier"), retryInfo("10s")}, true},
+		{"no retry", []map[string]interface{}{quotaFailure("GenerateRequestsPerMinutePerProjectPerModel")}, true},
+		{"retry only", []map[string]interface{}{retryInfo("1s")}, false},
+		{"malformed violations", []map[string]interface{}{{"@type": "type.googleapis.com/google.rpc.QuotaFailure", "violations": "x"}}, true},
+	}
+	for _, tt := range tests {
+		if got := isQuota(genai.APIError{Code: 429, Details: tt.details}); got != tt.want {
+			t.Errorf("%s: isQuota = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+// Package geminitest provides a local stand-in for the Gemini REST and Live
+// APIs so the tip and stream paths can run without network access. Point
+// the clients at it with GEMINI_BASE_URL and GEMINI_LIVE_URL.
+package geminitest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Response scripts one generateContent reply.
+type Response struct {
+	// Status is the HTTP status; zero means 200. Non-2xx statuses are sent
+	// as a Google API error body with ErrStatus (e.g. "UNAVAILABLE").
+	Status    int
+	ErrStatus string
+	Message   string
+	// Header is added to the response, e.g. Retry-After.
+	Header http.Header
+
+	// Tip is marshalled to JSON as the candidate text; otherwise Text is
+	// sent verbatim.
+	Tip  interface{}
+	Text string
+	// FinishReason defaults to "STOP".
+	FinishReason string
+
+	PromptTokens     int32
+	CandidatesTokens int32
+
+	// Delay is waited before replying; Reset drops the connection without
+	// a response.
+	Delay time.Duration
+	Reset bool
+}
+
+// Tip is the JSON shape the coaching prompt asks the model for.
+type Tip struct {
+	Text  string  `json:"text"`
+	Yaw   float64 `json:"yaw_deg,omitempty"`
+	Pitch float64 `json:"pitch_deg,omitempty"`
+	Roll  float64 `json:"roll_deg,omitempty"`
+}
+
+// Live scripts the Live API side: Advice is replied to frames in order
+// (cycling), and after ResetAfter frames the connection is dropped.
+type Live struct {
+	Advice     []string
+	ResetAfter int
+}
+
+// Request records a generateContent call.
+type Request struct {
+	Model string
+	Body  map[string]interface{}
+}
+
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	script   []Response
+	def      Response
+	live     Live
+	requests []Request
+	frames   int
+}
+
+// New returns an unstarted server for mounting Handler yourself. It
+// answers every call with a fixed tip until scripted otherwise.
+func New() *Server {
+	return &Server{
+		def:  Response{Tip: Tip{Text: "Lift your chin a little."}},
+		live: Live{Advice: []string{"Move a step to the left."}},
+	}
+}
+
+// NewServer starts New on a local port.
+func NewServer() *Server {
+	s := New()
+	s.Server = httptest.NewServer(s.Handler())
+	return s
+}
+
+// Handler serves the fake API; use it to mount the server elsewhere.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.serveREST)
+	mux.HandleFunc("/v1alpha/stream", s.serveLive)
+	return mux
+}
+
+// Enqueue appends replies used, in order, by the next calls.
+func (s *Server) Enqueue(rs ...Response) {
+	s.mu.Lock()
+	s.script = append(s.script, rs...)
+	s.mu.Unlock()
+}
+
+// SetDefault sets the reply used when the script is empty.
+func (s *Server) SetDefault(r Response) {
+	s.mu.Lock()
+	s.def = r
+	s.mu.Unlock()
+}
+
+func (s *Server) SetLive(l Live) {
+	s.mu.Lock()
+	s.live = l
+	s.mu.Unlock()
+}
+
+// Requests returns the generateContent calls received so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// LiveURL is the value for GEMINI_LIVE_URL.
+func (s *Server) LiveURL() string {
+	return "ws" + strings.TrimPrefix(s.URL, "http") + "/v1alpha/stream"
+}
+
+func (s *Server) next() Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.script) == 0 {
+		return s.def
+	}
+	r := s.script[0]
+	s.script = s.script[1:]
+	return r
+}
+
+func (s *Server) serveREST(w http.ResponseWriter, r *http.Request) {
+	i := strings.LastIndex(r.URL.Path, "/models/")
+	if r.Method != http.MethodPost || i < 0 || !strings.HasSuffix(r.URL.Path, ":generateContent") {
+		http.NotFound(w, r)
+		return
+	}
+	model := strings.TrimSuffix(r.URL.Path[i+len("/models/"):], ":generateContent")
+	var body map[string]interface{}
+	b, _ := io.ReadAll(r.Body)
+	_ = json.Unmarshal(b, &body)
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Model: model, Body: body})
+	s.mu.Unlock()
+
+	resp := s.next()
+	if resp.Delay > 0 {
+		select {
+		case <-time.After(resp.Delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if resp.Reset {
+		if hj, ok := w.(http.Hijacker); ok {
+			if c, _, err := hj.Hijack(); err == nil {
+				c.Close()
+				return
+			}
+		}
+		panic(http.ErrAbortHandler)
+	}
+	for k, v := range resp.Header {
+		w.Header()[k] = v
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if resp.Status != 0 && (resp.Status < 200 || resp.Status > 299) {
+		st := resp.ErrStatus
+		if st == "" {
+			st = http.StatusText(resp.Status)
+		}
+		w.WriteHeader(resp.Status)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": map[string]interface{}{"code": resp.Status, "message": resp.Message, "status": st},
+		})
+		return
+	}
+	text := resp.Text
+	if resp.Tip != nil {
+		tb, _ := json.Marshal(resp.Tip)
+		text = string(tb)
+	}
+	fr := resp.FinishReason
+	if fr == "" {
+		fr = "STOP"
+	}
+	cand := map[string]interface{}{"finishReason": fr, "index": 0}
+	if text != "" {
+		cand["content"] = map[string]interface{}{
+			"role":  "model",
+			"parts": []map[string]interface{}{{"text": text}},
+		}
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"candidates": []interface{}{cand},
+		"usageMetadata": map[string]interface{}{
+			"promptTokenCount":     resp.PromptTokens,
+			"candidatesTokenCount": resp.CandidatesTokens,
+			"totalTokenCount":      resp.PromptTokens + resp.CandidatesTokens,
+		},
+		"modelVersion": model,
+		"responseId":   "fake",
+	})
+}
+
+var upgrader = websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+
+func (s *Server) serveLive(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	// The first message is the setup with the system instruction.
+	if _, _, err := conn.ReadMessage(); err != nil {
+		return
+	}
+	for {
+		var msg struct {
+			Image *struct {
+				Data     string `json:"data"`
+				MimeType string `json:"mime_type"`
+			} `json:"image"`
+		}
+		if err := conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		if msg.Image == nil {
+			continue
+		}
+		s.mu.Lock()
+		s.frames++
+		n, live := s.frames, s.live
+		s.mu.Unlock()
+		if live.ResetAfter > 0 && n%live.ResetAfter == 0 {
+			return
+		}
+		if len(live.Advice) == 0 {
+			continue
+		}
+		text := live.Advice[(n-1)%len(live.Advice)]
+		err := conn.WriteJSON(map[string]interface{}{
+			"server_content": map[string]interface{}{
+				"model_turn": map[string]interface{}{
+					"parts": []map[string]interface{}{{"text": text}},
+				},
+			},
+		})
+		if err != nil {
+			return
+		}
+	}
+}
@@ -15,13 +15,20 @@
 package gemini
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
-	"log"
+	"fmt"
 	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gorilla/websocket"
+
+	"github.com/steveyiyo/hackyou-backend/internal/core/prompts"
 )
 
 // JSON Structures for API Communication
@@ -63,139 +70,332 @@ type receivedMessage struct {
 	ServerContent receivedServerContent `json:"server_content"`
 }
 
-// LiveClient manages the WebSocket connection to the Gemini Live API.
+// Defaults for LiveConfig.
+const (
+	DefaultLiveURL   = "wss://generativelanguage.googleapis.com/v1alpha/stream"
+	DefaultLiveModel = "gemini-live-2.5-flash-preview"
+
+	// DefaultLivePrompt asks for prompts.Ready once the shot is good, which
+	// the stream's ready detector treats as a capture trigger.
+	DefaultLivePrompt = "You are \"Frame-GPT\", a world-class professional photography assistant. Your purpose is to analyze incoming video frames and provide concise, actionable, and encouraging advice to help the user take better photos. Your analysis should focus on three key areas: 1. Composition: Adherence to rules like the rule of thirds, leading lines, framing, symmetry, and depth. 2. Lighting: Identify the quality and direction of light (e.g., soft, hard, backlighting, golden hour). Suggest adjustments to exposure or position. 3. Subject: Help the user clarify the main subject. Suggest ways to make the subject stand out, like adjusting depth of field or removing distractions. Your responses MUST be: - Concise: No more than 1-2 short sentences. - Actionable: Give a clear instruction, e.g., \"Try lowering the camera angle...\" instead of \"The angle is okay.\" - Real-time: Frame your advice based on the immediate image. - Encouraging: Use a positive and helpful tone. Do not greet the user or engage in small talk. Provide only direct, photographic advice. When the shot is ready to take, reply with exactly \"" + prompts.Ready + "\" and nothing else."
+)
+
+var (
+	ErrLiveNotStarted = errors.New("live client is not started")
+	ErrLiveClosed     = errors.New("live client is closed")
+	ErrLiveGaveUp     = errors.New("live client gave up reconnecting")
+)
+
+// LiveConfig configures a LiveClient. Zero fields take the defaults above.
+type LiveConfig struct {
+	URL          string
+	Model        string
+	APIKey       string
+	SystemPrompt string
+
+	// QueueSize bounds pending outgoing frames; when full the oldest frame
+	// is dropped. AdviceBuffer bounds unread advice, also dropping the
+	// oldest.
+	QueueSize    int
+	AdviceBuffer int
+
+	// MaxRetries consecutive failed reconnects end the client; zero means
+	// retry until closed. Backoff doubles from MinBackoff up to MaxBackoff.
+	MaxRetries int
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	Dialer *websocket.Dialer
+}
+
+type liveFrame struct {
+	data []byte
+	mime string
+}
+
+// LiveClient manages the WebSocket connection to the Gemini Live API. It
+// reconnects with exponential backoff when the connection drops and never
+// blocks callers: frames and advice are queued with bounded buffers.
 type LiveClient struct {
-	conn       *websocket.Conn
-	sendChan   chan []byte
+	cfg LiveConfig
+
+	sendChan   chan liveFrame
 	adviceChan chan string
-	doneChan   chan struct{}
-}
+	errChan    chan error
 
-// NewLiveClient creates a new, uninitialized LiveClient.
-func NewLiveClient() *LiveClient {
-	return &LiveClient{}
+	cancel    context.CancelFunc
+	doneChan  chan struct{}
+	closeOnce sync.Once
+	closed    atomic.Bool
+	gaveUp    atomic.Bool
+	connected atomic.Bool
+	dropped   atomic.Int64
 }
 
-// StartStreamingSession establishes a WebSocket connection and begins the streaming session.
-func (c *LiveClient) StartStreamingSession(apiKey string) error {
-	geminiURL := "wss://generativelanguage.googleapis.com/v1alpha/stream?model=gemini-live-2.5-flash-preview"
-	headers := http.Header{}
-	headers.Add("Authorization", "Bearer "+apiKey)
+// NewLiveClient creates a new, unstarted LiveClient.
+func NewLiveClient(cfg LiveConfig) *LiveClient {
+	if cfg.URL == "" {
+		cfg.URL = DefaultLiveURL
+	}
+	if cfg.Model == "" {
+		cfg.Model = DefaultLiveModel
+	}
+	if cfg.SystemPrompt == "" {
+		cfg.SystemPrompt = DefaultLivePrompt
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 4
+	}
+	if cfg.AdviceBuffer <= 0 {
+		cfg.AdviceBuffer = 16
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = 500 * time.Millisecond
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 30 * time.Second
+	}
+	if cfg.Dialer == nil {
+		cfg.Dialer = websocket.DefaultDialer
+	}
+	return &LiveClient{cfg: cfg}
+}
 
-	var err error
-	c.conn, _, err = websocket.DefaultDialer.Dial(geminiURL, headers)
+// StartStreamingSession connects and sends the initial configuration, then
+// keeps the session running in the background until ctx is done or Close
+// is called. Only the first connection attempt is reported as an error
+// here; later failures go to Errors.
+func (c *LiveClient) StartStreamingSession(ctx context.Context) error {
+	if c.doneChan != nil {
+		return errors.New("live client already started")
+	}
+	conn, err := c.dial(ctx)
 	if err != nil {
 		return err
 	}
-
-	// Initialize channels
-	c.sendChan = make(chan []byte)
-	c.adviceChan = make(chan string)
+	ctx, c.cancel = context.WithCancel(ctx)
+	c.sendChan = make(chan liveFrame, c.cfg.QueueSize)
+	c.adviceChan = make(chan string, c.cfg.AdviceBuffer)
+	c.errChan = make(chan error, 8)
 	c.doneChan = make(chan struct{})
+	go c.run(ctx, conn)
+	return nil
+}
+
+func (c *LiveClient) dial(ctx context.Context) (*websocket.Conn, error) {
+	u, err := url.Parse(c.cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	q.Set("model", c.cfg.Model)
+	u.RawQuery = q.Encode()
+	headers := http.Header{}
+	headers.Add("Authorization", "Bearer "+c.cfg.APIKey)
+
+	conn, _, err := c.cfg.Dialer.DialContext(ctx, u.String(), headers)
+	if err != nil {
+		return nil, err
+	}
 
 	// Send initial configuration
 	config := sendInitialConfig{
 		SystemInstruction: sendSystemInstruction{
-			Parts: []sendTextPart{
-				{Text: "You are \"Frame-GPT\", a world-class professional photography assistant. Your purpose is to analyze incoming video frames and provide concise, actionable, and encouraging advice to help the user take better photos. Your analysis should focus on three key areas: 1. Composition: Adherence to rules like the rule of thirds, leading lines, framing, symmetry, and depth. 2. Lighting: Identify the quality and direction of light (e.g., soft, hard, backlighting, golden hour). Suggest adjustments to exposure or position. 3. Subject: Help the user clarify the main subject. Suggest ways to make the subject stand out, like adjusting depth of field or removing distractions. Your responses MUST be: - Concise: No more than 1-2 short sentences. - Actionable: Give a clear instruction, e.g., \"Try lowering the camera angle...\" instead of \"The angle is okay.\" - Real-time: Frame your advice based on the immediate image. - Encouraging: Use a positive and helpful tone. Do not greet the user or engage in small talk. Provide only direct, photographic advice."},
-			},
+			Parts: []sendTextPart{{Text: c.cfg.SystemPrompt}},
 		},
 	}
-
-	configBytes, err := json.Marshal(config)
-	if err != nil {
-		return err
+	if err := conn.WriteJSON(config); err != nil {
+		conn.Close()
+		return nil, err
 	}
+	return conn, nil
+}
 
-	if err := c.conn.WriteMessage(websocket.TextMessage, configBytes); err != nil {
-		return err
+// run serves conn and reconnects until ctx is done or retries run out.
+// Frames sent after it returns are refused.
+func (c *LiveClient) run(ctx context.Context, conn *websocket.Conn) {
+	defer close(c.doneChan)
+	defer c.closed.Store(true)
+	defer close(c.adviceChan)
+	defer close(c.errChan)
+	for {
+		err := c.serve(ctx, conn)
+		if ctx.Err() != nil {
+			return
+		}
+		c.report(err)
+		conn = c.reconnect(ctx)
+		if conn == nil {
+			return
+		}
 	}
+}
 
-	// Launch concurrent read/write handlers
-	go c.readMessages()
-	go c.writeMessages()
-
+func (c *LiveClient) reconnect(ctx context.Context) *websocket.Conn {
+	backoff := c.cfg.MinBackoff
+	for attempt := 1; c.cfg.MaxRetries <= 0 || attempt <= c.cfg.MaxRetries; attempt++ {
+		t := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil
+		case <-t.C:
+		}
+		conn, err := c.dial(ctx)
+		if err == nil {
+			return conn
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		c.report(fmt.Errorf("reconnect attempt %d: %w", attempt, err))
+		backoff = min(backoff*2, c.cfg.MaxBackoff)
+	}
+	c.gaveUp.Store(true)
+	c.report(ErrLiveGaveUp)
 	return nil
 }
 
-// readMessages runs in a goroutine, continuously reading from the WebSocket.
-func (c *LiveClient) readMessages() {
-	defer close(c.adviceChan)
+// serve pumps frames to conn and advice from it until either side fails or
+// ctx is done. It returns only after the reader has stopped, so run may
+// close the advice and error channels.
+func (c *LiveClient) serve(ctx context.Context, conn *websocket.Conn) error {
+	c.connected.Store(true)
+	defer c.connected.Store(false)
+	readErr := make(chan error, 1)
+	go func() { readErr <- c.readMessages(conn) }()
+	reading := true
+	defer func() {
+		conn.Close()
+		if reading {
+			<-readErr
+		}
+	}()
 	for {
-		_, message, err := c.conn.ReadMessage()
+		select {
+		case <-ctx.Done():
+			// Cleanly close the connection by sending a close message.
+			_ = conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+				time.Now().Add(time.Second))
+			return ctx.Err()
+		case err := <-readErr:
+			reading = false
+			return err
+		case f := <-c.sendChan:
+			conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			err := conn.WriteJSON(sendImageFrame{
+				Image: sendImagePart{
+					DataBase64: base64.StdEncoding.EncodeToString(f.data),
+					MimeType:   f.mime,
+				},
+			})
+			if err != nil {
+				return fmt.Errorf("write: %w", err)
+			}
+		}
+	}
+}
+
+// readMessages reads from conn until it fails.
+func (c *LiveClient) readMessages(conn *websocket.Conn) error {
+	for {
+		_, message, err := conn.ReadMessage()
 		if err != nil {
-			log.Println("read error:", err)
-			return
+			return fmt.Errorf("read: %w", err)
 		}
 
 		var received receivedMessage
 		if err := json.Unmarshal(message, &received); err != nil {
-			log.Println("unmarshal error:", err)
+			c.report(fmt.Errorf("unmarshal: %w", err))
 			continue
 		}
 
 		if len(received.ServerContent.ModelTurn.Parts) > 0 {
-			c.adviceChan <- received.ServerContent.ModelTurn.Parts[0].Text
+			c.deliver(received.ServerContent.ModelTurn.Parts[0].Text)
 		}
 	}
 }
 
-// writeMessages runs in a goroutine, handling outgoing messages.
-func (c *LiveClient) writeMessages() {
+func (c *LiveClient) deliver(text string) {
 	for {
 		select {
-		case frame := <-c.sendChan:
-			imgFrame := sendImageFrame{
-				Image: sendImagePart{
-					DataBase64: base64.StdEncoding.EncodeToString(frame),
-					MimeType:   "image/jpeg",
-				},
-			}
-			frameBytes, err := json.Marshal(imgFrame)
-			if err != nil {
-				log.Println("marshal error:", err)
-				continue
-			}
-
-			if err := c.conn.WriteMessage(websocket.TextMessage, frameBytes); err != nil {
-				log.Println("write error:", err)
-			}
-		case <-c.doneChan:
-			// Cleanly close the connection by sending a close message and then waiting
-			// for the server to close the connection.
-			log.Println("closing connection")
-			err := c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
-			if err != nil {
-				log.Println("write close:", err)
-			}
+		case c.adviceChan <- text:
 			return
+		default:
+		}
+		select {
+		case <-c.adviceChan:
+		default:
 		}
 	}
 }
 
-// SendImageFrame sends a single image frame over the WebSocket connection.
-func (c *LiveClient) SendImageFrame(frame []byte) error {
+func (c *LiveClient) report(err error) {
+	select {
+	case c.errChan <- err:
+	default:
+	}
+}
+
+// SendImageFrame queues a single image frame. It never blocks; if the queue
+// is full the oldest pending frame is dropped. Once the client has stopped
+// it returns ErrLiveGaveUp or ErrLiveClosed.
+func (c *LiveClient) SendImageFrame(frame []byte, mime string) error {
 	if c.sendChan == nil {
-		return errors.New("client is not started")
+		return ErrLiveNotStarted
+	}
+	if c.gaveUp.Load() {
+		return ErrLiveGaveUp
+	}
+	if c.closed.Load() {
+		return ErrLiveClosed
+	}
+	f := liveFrame{data: frame, mime: mime}
+	for {
+		select {
+		case c.sendChan <- f:
+			return nil
+		default:
+		}
+		select {
+		case <-c.sendChan:
+			c.dropped.Add(1)
+		default:
+		}
 	}
-	c.sendChan <- frame
-	return nil
 }
 
-// ReceiveAdvice returns a channel that streams text advice from Gemini.
+// Connected reports whether the client is connected now, rather than
+// reconnecting or stopped.
+func (c *LiveClient) Connected() bool { return c.connected.Load() }
+
+// Dropped reports how many frames were discarded because the queue was full.
+func (c *LiveClient) Dropped() int64 { return c.dropped.Load() }
+
+// ReceiveAdvice returns a channel that streams text advice from Gemini. It
+// is closed when the client stops.
 func (c *LiveClient) ReceiveAdvice() (<-chan string, error) {
 	if c.adviceChan == nil {
-		return nil, errors.New("client is not started")
+		return nil, ErrLiveNotStarted
 	}
 	return c.adviceChan, nil
 }
 
-// Close shuts down the client and closes the WebSocket connection.
+// Errors returns a channel of connection and protocol errors. Errors are
+// dropped if nobody reads them. It is closed when the client stops.
+func (c *LiveClient) Errors() <-chan error {
+	return c.errChan
+}
+
+// Close shuts down the client and waits for the connection to close. It is
+// safe to call more than once.
 func (c *LiveClient) Close() {
-	if c.doneChan != nil {
-		close(c.doneChan)
-	}
-	if c.conn != nil {
-		c.conn.Close()
-	}
-}
\ No newline at end of file
+	c.closeOnce.Do(func() {
+		c.closed.Store(true)
+		if c.cancel != nil {
+			c.cancel()
+			<-c.doneChan
+		}
+	})
+}
@@ -0,0 +1,172 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/steveyiyo/hackyou-backend/internal/core/gemini/geminitest"
+)
+
+// flakyLive serves fake as the Live API until down is set, then refuses
+// connections.
+type flakyLive struct {
+	*httptest.Server
+	fake *geminitest.Server
+	down atomic.Bool
+}
+
+func newFlakyLive(t *testing.T) *flakyLive {
+	t.Helper()
+	f := &flakyLive{fake: geminitest.New()}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if f.down.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		f.fake.Handler().ServeHTTP(w, r)
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *flakyLive) client(maxRetries int) *LiveClient {
+	return NewLiveClient(LiveConfig{
+		URL:        "ws" + strings.TrimPrefix(f.URL, "http") + "/v1alpha/stream",
+		APIKey:     "test-key",
+		MaxRetries: maxRetries,
+		MinBackoff: 5 * time.Millisecond,
+		MaxBackoff: 20 * time.Millisecond,
+	})
+}
+
+func startLive(t *testing.T, c *LiveClient) <-chan string {
+	t.Helper()
+	if err := c.StartStreamingSession(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(c.Close)
+	advice, err := c.ReceiveAdvice()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return advice
+}
+
+func nextAdvice(t *testing.T, advice <-chan string) (string, bool) {
+	t.Helper()
+	select {
+	case a, ok := <-advice:
+		return a, ok
+	case <-time.After(2 * time.Second):
+		t.Fatal("no advice")
+		return "", false
+	}
+}
+
+func TestLiveClientReconnects(t *testing.T) {
+	f := newFlakyLive(t)
+	f.fake.SetLive(geminitest.Live{Advice: []string{"one", "two", "three"}, ResetAfter: 2})
+	c := f.client(0)
+	advice := startLive(t, c)
+
+	c.SendImageFrame([]byte("1"), "image/jpeg")
+	if a, _ := nextAdvice(t, advice); a != "one" {
+		t.Fatalf("advice = %q", a)
+	}
+	// The second frame drops the connection; the client reconnects and
+	// the third is answered on the new one.
+	c.SendImageFrame([]byte("2"), "image/jpeg")
+	select {
+	case err := <-c.Errors():
+		if err == nil {
+			t.Fatal("nil error reported")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("dropped connection not reported")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for !c.Connected() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	c.SendImageFrame([]byte("3"), "image/jpeg")
+	if a, _ := nextAdvice(t, advice); a != "three" {
+		t.Fatalf("advice after reconnect = %q", a)
+	}
+}
+
+func TestLiveClientGivesUp(t *testing.T) {
+	f := newFlakyLive(t)
+	c := f.client(2)
+	advice := startLive(t, c)
+
+	f.down.Store(true)
+	f.fake.SetLive(geminitest.Live{ResetAfter: 1})
+	c.SendImageFrame([]byte("1"), "image/jpeg")
+	if _, ok := nextAdvice(t, advice); ok {
+		t.Fatal("advice after the server went down")
+	}
+	var last error
+	for err := range c.Errors() {
+		last = err
+	}
+	if !errors.Is(last, ErrLiveGaveUp) {
+		t.Errorf("last error = %v, want %v", last, ErrLiveGaveUp)
+	}
+	if c.Connected() {
+		t.Error("connected after giving up")
+	}
+	if err := c.SendImageFrame([]byte("2"), "image/jpeg"); !errors.Is(err, ErrLiveGaveUp) {
+		t.Errorf("SendImageFrame = %v, want %v", err, ErrLiveGaveUp)
+	}
+}
+
+func TestLiveClientClose(t *testing.T) {
+	f := newFlakyLive(t)
+	c := f.client(0)
+	if err := c.SendImageFrame([]byte("1"), "image/jpeg"); !errors.Is(err, ErrLiveNotStarted) {
+		t.Errorf("SendImageFrame before start = %v", err)
+	}
+	advice := startLive(t, c)
+	c.Close()
+	c.Close()
+	if _, ok := <-advice; ok {
+		t.Error("advice channel open after Close")
+	}
+	if err := c.SendImageFrame([]byte("1"), "image/jpeg"); !errors.Is(err, ErrLiveClosed) {
+		t.Errorf("SendImageFrame after Close = %v, want %v", err, ErrLiveClosed)
+	}
+}
+
+func TestLiveClientDropsOldestFrame(t *testing.T) {
+	c := NewLiveClient(LiveConfig{QueueSize: 2})
+	// Started, but with nothing draining the queue.
+	c.sendChan = make(chan liveFrame, c.cfg.QueueSize)
+	for _, b := range []string{"1", "2", "3"} {
+		if err := c.SendImageFrame([]byte(b), "image/jpeg"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := c.Dropped(); n != 1 {
+		t.Errorf("Dropped = %d, want 1", n)
+	}
+	if f := <-c.sendChan; string(f.data) != "2" {
+		t.Errorf("oldest queued frame = %q, want 2", f.data)
+	}
+}
+
+func TestLiveClientDropsOldestAdvice(t *testing.T) {
+	c := NewLiveClient(LiveConfig{AdviceBuffer: 2})
+	c.adviceChan = make(chan string, c.cfg.AdviceBuffer)
+	for _, a := range []string{"one", "two", "three"} {
+		c.deliver(a)
+	}
+	if a, b := <-c.adviceChan, <-c.adviceChan; a != "two" || b != "three" {
+		t.Errorf("advice = %q, %q; want two, three", a, b)
+	}
+}
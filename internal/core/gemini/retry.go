@@ -0,0 +1,142 @@
+package gemini
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RetryPolicy bounds how a Gemini call is repeated. Waits grow
+// exponentially from BaseDelay up to MaxDelay, are spread by ±Jitter (a
+// fraction of the wait) and are replaced by the server's Retry-After hint
+// when it sends one. All attempts and waits of a call fit within Budget;
+// a wait that would overrun it, or the caller's deadline, ends the call
+// with the last error instead.
+type RetryPolicy struct {
+	MaxAttempts int
+	Budget      time.Duration
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Jitter      float64
+}
+
+// DefaultRetryPolicy is used for zero fields of Client.Retry. Its Budget
+// fits within the stream's default tip interval.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	Budget:      1500 * time.Millisecond,
+	BaseDelay:   200 * time.Millisecond,
+	MaxDelay:    time.Second,
+	Jitter:      0.2,
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	d := DefaultRetryPolicy
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = d.MaxAttempts
+	}
+	if p.Budget <= 0 {
+		p.Budget = d.Budget
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = d.BaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = d.MaxDelay
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		p.Jitter = d.Jitter
+	}
+	return p
+}
+
+// delay is the wait before attempt n+1 after attempt n (from 1) failed
+// with e.
+func (p RetryPolicy) delay(n int, e *Error) time.Duration {
+	if e != nil && e.RetryAfter > 0 {
+		return e.RetryAfter
+	}
+	d := p.BaseDelay << (n - 1)
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		d += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(d))
+	}
+	return d
+}
+
+// wait sleeps for d unless ctx ends first or d would run past its
+// deadline, in which case it returns false at once.
+func wait(ctx context.Context, d time.Duration) bool {
+	if dl, ok := ctx.Deadline(); ok && time.Until(dl) < d {
+		return false
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+// retryHint carries the Retry-After header of an attempt's response from
+// the transport back to the retry loop.
+type retryHint struct {
+	mu sync.Mutex
+	d  time.Duration
+}
+
+type retryHintKey struct{}
+
+func withRetryHint(ctx context.Context) (context.Context, *retryHint) {
+	h := &retryHint{}
+	return context.WithValue(ctx, retryHintKey{}, h), h
+}
+
+func (h *retryHint) get() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.d
+}
+
+// retryAfterTransport records Retry-After on the request's retryHint.
+type retryAfterTransport struct {
+	base http.RoundTripper
+}
+
+func (t *retryAfterTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(r)
+	if err != nil {
+		return resp, err
+	}
+	if h, ok := r.Context().Value(retryHintKey{}).(*retryHint); ok {
+		if d := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); d > 0 {
+			h.mu.Lock()
+			h.d = d
+			h.mu.Unlock()
+		}
+	}
+	return resp, nil
+}
+
+// parseRetryAfter accepts delay-seconds or an HTTP date.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	if n, err := strconv.Atoi(v); err == nil {
+		return time.Duration(n) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return t.Sub(now)
+	}
+	return 0
+}
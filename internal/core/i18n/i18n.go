@@ -0,0 +1,99 @@
+// Package i18n translates the fixed tip texts produced without a model,
+// keyed by message ID and BCP-47 locale.
+package i18n
+
+import (
+	"golang.org/x/text/language"
+)
+
+// Catalog holds messages per locale. A locale it does not support is
+// matched to the closest one it does; if none is close, the Fallback
+// locales are tried in order, then the first locale of the catalog.
+type Catalog struct {
+	tags     []language.Tag
+	msgs     []map[string]string
+	matcher  language.Matcher
+	fallback []language.Tag
+}
+
+// DefaultFallback is used when New is given no fallback locales.
+const DefaultFallback = "en"
+
+// New returns the built-in catalog with the given fallback chain. Invalid
+// or unsupported fallback locales are ignored.
+func New(fallback ...string) *Catalog {
+	c := &Catalog{}
+	for _, loc := range builtinOrder {
+		c.tags = append(c.tags, language.MustParse(loc))
+		c.msgs = append(c.msgs, builtin[loc])
+	}
+	c.matcher = language.NewMatcher(c.tags)
+	if len(fallback) == 0 {
+		fallback = []string{DefaultFallback}
+	}
+	for _, f := range fallback {
+		if t, err := language.Parse(f); err == nil && c.index(t) >= 0 {
+			c.fallback = append(c.fallback, t)
+		}
+	}
+	return c
+}
+
+var std = New()
+
+// Default returns the built-in catalog falling back to DefaultFallback.
+func Default() *Catalog { return std }
+
+func (c *Catalog) index(t language.Tag) int {
+	for i, s := range c.tags {
+		if s == t {
+			return i
+		}
+	}
+	return -1
+}
+
+// Printer looks up messages for one resolved locale.
+type Printer struct {
+	c     *Catalog
+	chain []int
+}
+
+// Printer resolves locale. A locale without a close match, e.g. one in a
+// different script, resolves to the fallback chain, which also supplies
+// messages missing from the resolved locale.
+func (c *Catalog) Printer(locale string) Printer {
+	p := Printer{c: c}
+	if t, err := language.Parse(locale); err == nil {
+		if _, i, conf := c.matcher.Match(t); conf >= language.High {
+			p.chain = append(p.chain, i)
+		}
+	}
+	for _, f := range c.fallback {
+		p.chain = append(p.chain, c.index(f))
+	}
+	return p.add(0)
+}
+
+func (p Printer) add(i int) Printer {
+	for _, j := range p.chain {
+		if j == i {
+			return p
+		}
+	}
+	p.chain = append(p.chain, i)
+	return p
+}
+
+// Locale is the locale messages are printed in.
+func (p Printer) Locale() language.Tag { return p.c.tags[p.chain[0]] }
+
+// Text returns message key, or key itself if no locale in the chain has it.
+func (p Printer) Text(key string) string {
+	for _, i := range p.chain {
+		if s, ok := p.c.msgs[i][key]; ok {
+			return s
+		}
+	}
+	return key
+}
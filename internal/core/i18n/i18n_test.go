@@ -0,0 +1,25 @@
+package i18n
+
+import "testing"
+
+func TestPrinterLocale(t *testing.T) {
+	c := New("ja", "en")
+	tests := []struct {
+		locale, want string
+	}{
+		{"zh-TW", "zh-TW"},
+		{"zh-Hant-HK", "zh-TW"},
+		{"en-GB", "en"},
+		{"ja-JP", "ja"},
+		// Simplified Chinese is only a low-confidence match for zh-TW.
+		{"zh-CN", "ja"},
+		{"zh", "ja"},
+		{"fr", "ja"},
+		{"", "ja"},
+	}
+	for _, tt := range tests {
+		if got := c.Printer(tt.locale).Locale().String(); got != tt.want {
+			t.Errorf("Printer(%q).Locale() = %s, want %s", tt.locale, got, tt.want)
+		}
+	}
+}
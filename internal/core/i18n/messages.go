@@ -0,0 +1,53 @@
+package i18n
+
+// Message IDs.
+const (
+	MsgConnecting   = "connecting"
+	MsgBlurry       = "blurry"
+	MsgUnderexposed = "underexposed"
+	MsgOverexposed  = "overexposed"
+	MsgHighlights   = "blown_highlights"
+	MsgShadows      = "crushed_shadows"
+	MsgTiltedCW     = "tilted_cw"
+	MsgTiltedCCW    = "tilted_ccw"
+	MsgGood         = "good"
+)
+
+// builtinOrder lists the built-in locales; the first is the last resort.
+var builtinOrder = []string{"en", "zh-TW", "ja"}
+
+var builtin = map[string]map[string]string{
+	"en": {
+		MsgConnecting:   "Connecting to your coach ...",
+		MsgBlurry:       "Hold the phone steady, the shot is blurry.",
+		MsgUnderexposed: "Too dark. Face the light or add some light.",
+		MsgOverexposed:  "Too bright. Move out of direct light.",
+		MsgHighlights:   "Highlights are blown out. Lower the exposure a little.",
+		MsgShadows:      "Shadows are too deep. Turn toward the light.",
+		MsgTiltedCW:     "Level the camera: rotate it slightly clockwise.",
+		MsgTiltedCCW:    "Level the camera: rotate it slightly counter-clockwise.",
+		MsgGood:         "Looks good, hold steady.",
+	},
+	"zh-TW": {
+		MsgConnecting:   "攝影教練連線中 ...",
+		MsgBlurry:       "畫面模糊，請拿穩手機。",
+		MsgUnderexposed: "太暗了，請面向光源或補光。",
+		MsgOverexposed:  "太亮了，請避開直射光。",
+		MsgHighlights:   "亮部過曝，請稍微降低曝光。",
+		MsgShadows:      "陰影太重，請轉向光源。",
+		MsgTiltedCW:     "請把相機轉正：稍微順時針旋轉。",
+		MsgTiltedCCW:    "請把相機轉正：稍微逆時針旋轉。",
+		MsgGood:         "很好，保持不動。",
+	},
+	"ja": {
+		MsgConnecting:   "コーチに接続中 ...",
+		MsgBlurry:       "ブレています。スマホをしっかり持ってください。",
+		MsgUnderexposed: "暗すぎます。光の方を向くか、明かりを足してください。",
+		MsgOverexposed:  "明るすぎます。直射日光を避けてください。",
+		MsgHighlights:   "白飛びしています。露出を少し下げてください。",
+		MsgShadows:      "影が濃すぎます。光の方へ向いてください。",
+		MsgTiltedCW:     "カメラを水平に：少し時計回りに回してください。",
+		MsgTiltedCCW:    "カメラを水平に：少し反時計回りに回してください。",
+		MsgGood:         "いい感じです。そのままキープ。",
+	},
+}
@@ -0,0 +1,228 @@
+// Package prompts holds the coaching prompts sent to the model, one
+// text/template per shooting mode and locale. Templates live under
+// templates/<mode>/<locale>.tmpl; files starting with "_" define shared
+// templates such as "format" and "rating". The embedded set can be extended or
+// overridden from a directory with the same layout.
+package prompts
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+)
+
+//go:embed all:templates
+var embedded embed.FS
+
+const (
+	DefaultMode   = "portrait"
+	DefaultLocale = "en"
+
+	// Ready is what the model is told to answer once the shot is good.
+	Ready = "Ready!"
+)
+
+// Vars are the template variables. Mode is the prompt's mode; Locale is
+// the session's, which the prompt's locale may only approximate, and
+// Language its English name, e.g. "Japanese (Japan)", for prompts to ask
+// for replies in.
+type Vars struct {
+	Mode     string
+	Locale   string
+	Language string
+	Ready    string
+	History  []Turn
+}
+
+// Turn is an earlier tip of the session; Followed is "yes", "no" or
+// "unknown".
+type Turn struct {
+	Text     string
+	AgoSec   int64
+	Followed string
+}
+
+// Prompt is one mode and locale's template. Version changes whenever the
+// template source does.
+type Prompt struct {
+	Mode    string
+	Locale  language.Tag
+	Version string
+	tmpl    *template.Template
+}
+
+type modeSet struct {
+	prompts []*Prompt
+	matcher language.Matcher
+}
+
+// Registry looks up prompts by mode and locale.
+type Registry struct {
+	modes map[string]*modeSet
+}
+
+// Default returns the embedded prompts.
+func Default() *Registry {
+	r, err := Load("")
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Load returns the embedded prompts extended by those under dir, which win
+// on conflicts. Empty dir loads only the embedded ones.
+func Load(dir string) (*Registry, error) {
+	base, _ := fs.Sub(embedded, "templates")
+	srcs := map[string]map[string]string{}
+	shared := []string{}
+	add := func(fsys fs.FS) error {
+		return fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || path.Ext(p) != ".tmpl" {
+				return err
+			}
+			b, err := fs.ReadFile(fsys, p)
+			if err != nil {
+				return err
+			}
+			name := strings.TrimSuffix(path.Base(p), ".tmpl")
+			if strings.HasPrefix(name, "_") {
+				shared = append(shared, string(b))
+				return nil
+			}
+			mode := path.Dir(p)
+			if mode == "." || strings.Contains(mode, "/") {
+				return fmt.Errorf("prompts: %s: want <mode>/<locale>.tmpl", p)
+			}
+			mode = strings.ToLower(mode)
+			if srcs[mode] == nil {
+				srcs[mode] = map[string]string{}
+			}
+			srcs[mode][name] = string(b)
+			return nil
+		})
+	}
+	if err := add(base); err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		if err := add(os.DirFS(dir)); err != nil {
+			return nil, err
+		}
+	}
+
+	r := &Registry{modes: map[string]*modeSet{}}
+	for mode, locales := range srcs {
+		set := &modeSet{}
+		for loc, src := range locales {
+			tag, err := language.Parse(loc)
+			if err != nil {
+				return nil, fmt.Errorf("prompts: %s/%s.tmpl: %w", mode, loc, err)
+			}
+			t := template.New(mode + "/" + loc).Option("missingkey=error")
+			for _, s := range shared {
+				if _, err := t.Parse(s); err != nil {
+					return nil, fmt.Errorf("prompts: shared template: %w", err)
+				}
+			}
+			if _, err := t.Parse(src); err != nil {
+				return nil, fmt.Errorf("prompts: %s/%s.tmpl: %w", mode, loc, err)
+			}
+			sum := sha256.Sum256([]byte(strings.Join(append([]string{src}, shared...), "\x00")))
+			set.prompts = append(set.prompts, &Prompt{Mode: mode, Locale: tag, Version: hex.EncodeToString(sum[:4]), tmpl: t})
+		}
+		// The matcher falls back to its first tag, so the default locale
+		// goes first.
+		sort.SliceStable(set.prompts, func(i, j int) bool {
+			return set.prompts[i].Locale.String() == DefaultLocale && set.prompts[j].Locale.String() != DefaultLocale
+		})
+		tags := make([]language.Tag, len(set.prompts))
+		for i, p := range set.prompts {
+			tags[i] = p.Locale
+		}
+		set.matcher = language.NewMatcher(tags)
+		r.modes[mode] = set
+	}
+	if r.modes[DefaultMode] == nil {
+		return nil, fmt.Errorf("prompts: no %s prompts", DefaultMode)
+	}
+	return r, nil
+}
+
+// Modes lists the known modes.
+func (r *Registry) Modes() []string {
+	out := make([]string, 0, len(r.modes))
+	for m := range r.modes {
+		out = append(out, m)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Lookup returns the prompt for mode in the locale closest to locale. An
+// unknown mode uses DefaultMode; a locale without a close match, e.g. a
+// different script, uses the mode's DefaultLocale prompt, or its first
+// one.
+func (r *Registry) Lookup(mode, locale string) *Prompt {
+	set := r.modes[strings.ToLower(mode)]
+	if set == nil {
+		set = r.modes[DefaultMode]
+	}
+	tag, _ := language.Parse(locale)
+	_, i, conf := set.matcher.Match(tag)
+	if conf < language.High {
+		i = 0
+	}
+	return set.prompts[i]
+}
+
+// Render looks up the prompt for mode and locale and executes it with the
+// session's earlier tips.
+func (r *Registry) Render(mode, locale string, history []Turn) (string, *Prompt, error) {
+	p := r.Lookup(mode, locale)
+	s, err := p.execute("", locale, history)
+	return s, p, err
+}
+
+// RenderRating is Render for the shared "rating" template, which asks for
+// a score of a finished photo.
+func (r *Registry) RenderRating(mode, locale string) (string, *Prompt, error) {
+	p := r.Lookup(mode, locale)
+	s, err := p.execute("rating", locale, nil)
+	return s, p, err
+}
+
+// execute runs template name, or the prompt itself when empty.
+func (p *Prompt) execute(name, locale string, history []Turn) (string, error) {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		tag = language.MustParse(DefaultLocale)
+	}
+	v := Vars{
+		Mode:     p.Mode,
+		Locale:   tag.String(),
+		Language: display.English.Tags().Name(tag),
+		Ready:    Ready,
+		History:  history,
+	}
+	var b strings.Builder
+	if name == "" {
+		err = p.tmpl.Execute(&b, v)
+	} else {
+		err = p.tmpl.ExecuteTemplate(&b, name, v)
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(b.String()), nil
+}
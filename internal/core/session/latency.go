@@ -0,0 +1,50 @@
+package session
+
+import (
+	"sort"
+
+	"github.com/steveyiyo/hackyou-backend/pkg/types"
+)
+
+func latencySummary(samples []types.LatencySample) types.LatencySummary {
+	var f2t, prov []int64
+	bySrc := map[string][2][]int64{}
+	for _, l := range samples {
+		f2t = append(f2t, l.FrameToTipMs)
+		b := bySrc[l.Source]
+		b[0] = append(b[0], l.FrameToTipMs)
+		if l.ProviderMs != nil {
+			prov = append(prov, *l.ProviderMs)
+			b[1] = append(b[1], *l.ProviderMs)
+		}
+		bySrc[l.Source] = b
+	}
+	out := types.LatencySummary{
+		LatencyBreakdown: types.LatencyBreakdown{FrameToTip: stats(f2t), Provider: stats(prov)},
+		BySource:         map[string]types.LatencyBreakdown{},
+	}
+	for src, b := range bySrc {
+		out.BySource[src] = types.LatencyBreakdown{FrameToTip: stats(b[0]), Provider: stats(b[1])}
+	}
+	return out
+}
+
+// stats uses the nearest-rank method; v is sorted in place.
+func stats(v []int64) types.LatencyStats {
+	if len(v) == 0 {
+		return types.LatencyStats{}
+	}
+	sort.Slice(v, func(i, j int) bool { return v[i] < v[j] })
+	rank := func(p int) int64 {
+		i := (p*len(v)+99)/100 - 1
+		return v[max(i, 0)]
+	}
+	return types.LatencyStats{
+		Count: len(v),
+		P50:   rank(50),
+		P90:   rank(90),
+		P99:   rank(99),
+		Min:   v[0],
+		Max:   v[len(v)-1],
+	}
+}
@@ -0,0 +1,50 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/steveyiyo/hackyou-backend/pkg/types"
+)
+
+func TestStats(t *testing.T) {
+	seq := func(n int) []int64 {
+		v := make([]int64, n)
+		for i := range v {
+			v[i] = int64(n - i)
+		}
+		return v
+	}
+	tests := []struct {
+		name string
+		v    []int64
+		want types.LatencyStats
+	}{
+		{"empty", nil, types.LatencyStats{}},
+		{"one", []int64{7}, types.LatencyStats{Count: 1, P50: 7, P90: 7, P99: 7, Min: 7, Max: 7}},
+		{"two", []int64{20, 10}, types.LatencyStats{Count: 2, P50: 10, P90: 20, P99: 20, Min: 10, Max: 20}},
+		{"ten", seq(10), types.LatencyStats{Count: 10, P50: 5, P90: 9, P99: 10, Min: 1, Max: 10}},
+		{"hundred", seq(100), types.LatencyStats{Count: 100, P50: 50, P90: 90, P99: 99, Min: 1, Max: 100}},
+		{"ties", []int64{3, 1, 3, 3}, types.LatencyStats{Count: 4, P50: 3, P90: 3, P99: 3, Min: 1, Max: 3}},
+	}
+	for _, tt := range tests {
+		if got := stats(tt.v); got != tt.want {
+			t.Errorf("%s: stats = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLatencySummarySkipsUnknownProviderTime(t *testing.T) {
+	ms := func(v int64) *int64 { return &v }
+	got := latencySummary([]types.LatencySample{
+		{Source: "gemini", FrameToTipMs: 900, ProviderMs: ms(800)},
+		{Source: "gemini_live", FrameToTipMs: 300},
+		{Source: "heuristic", FrameToTipMs: 40, ProviderMs: ms(0)},
+	})
+	if got.FrameToTip.Count != 3 || got.Provider.Count != 2 || got.Provider.Max != 800 {
+		t.Errorf("summary = %+v", got.LatencyBreakdown)
+	}
+	live := got.BySource["gemini_live"]
+	if live.FrameToTip.Count != 1 || live.Provider != (types.LatencyStats{}) {
+		t.Errorf("gemini_live = %+v", live)
+	}
+}
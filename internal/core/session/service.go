@@ -1,47 +1,129 @@
 package session
 
 import (
+	"context"
 	"time"
 
-	"github.com/steveyiyo/hackyou-backend/internal/repo/memory"
+	"github.com/steveyiyo/hackyou-backend/internal/repo"
 	"github.com/steveyiyo/hackyou-backend/pkg/types"
 
 	"github.com/google/uuid"
 )
 
+const (
+	EndClient  = "client"
+	EndIdle    = "idle"
+	EndExpired = "expired"
+)
+
 type Service struct {
-	Repo *memory.SessionRepo
+	Repo repo.SessionRepository
+
+	TTL       time.Duration
+	Idle      time.Duration
+	Retention time.Duration
+
+	// OnEnd is called after a session has been marked ended, e.g. to close
+	// its stream connection.
+	OnEnd func(id, reason string)
 }
 
-func NewService(repo *memory.SessionRepo) *Service {
-	return &Service{Repo: repo}
+func NewService(r repo.SessionRepository) *Service {
+	return &Service{Repo: r}
 }
 
-func (s *Service) Create(mode, locale string, device map[string]string, consent map[string]bool) *memory.Session {
+func (s *Service) Create(owner string, req types.CreateSessionReq) *types.Session {
 	id := "sess_" + uuid.NewString()
-	sess := &memory.Session{
-		ID:         id,
-		CreatedAt:  time.Now(),
-		Mode:       mode,
-		Locale:     locale,
-		Device:     device,
-		Consent:    consent,
-		Tips:       []types.Tip{},
-		LatencyP50: 380,
+	now := time.Now()
+	coaching := req.Coaching
+	if coaching == "" {
+		coaching = types.CoachingInterval
+	}
+	sess := &types.Session{
+		ID:          id,
+		Owner:       owner,
+		CreatedAt:   now,
+		LastSeenAt:  now,
+		Mode:        req.Mode,
+		Locale:      req.Locale,
+		Coaching:    coaching,
+		Device:      req.Device,
+		Consent:     req.Consent,
+		AutoShutter: req.AutoShutter,
+		Tips:        []types.Tip{},
 	}
 	s.Repo.Save(sess)
 	return sess
 }
 
+// End marks the session ended with the given reason. It returns false if the
+// session does not exist or was already ended.
+func (s *Service) End(id, reason string) bool {
+	if !s.Repo.End(id, reason, time.Now()) {
+		return false
+	}
+	if s.OnEnd != nil {
+		s.OnEnd(id, reason)
+	}
+	return true
+}
+
+// RunJanitor ends sessions that outlived TTL or have been idle for longer
+// than Idle, and deletes ended sessions after Retention. It blocks until ctx
+// is done.
+func (s *Service) RunJanitor(ctx context.Context, every time.Duration) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.C:
+			s.sweep(now)
+		}
+	}
+}
+
+func (s *Service) sweep(now time.Time) {
+	for _, sess := range s.Repo.List() {
+		switch {
+		case sess.Ended():
+			if s.Retention > 0 && now.Sub(sess.EndedAt) > s.Retention {
+				s.Repo.Delete(sess.ID)
+			}
+		case s.TTL > 0 && now.Sub(sess.CreatedAt) > s.TTL:
+			s.End(sess.ID, EndExpired)
+		case s.Idle > 0 && now.Sub(sess.LastSeenAt) > s.Idle:
+			s.End(sess.ID, EndIdle)
+		}
+	}
+}
+
 func (s *Service) Summary(id string) (types.SummaryResp, bool) {
 	sess, ok := s.Repo.Get(id)
 	if !ok {
 		return types.SummaryResp{}, false
 	}
-	return types.SummaryResp{
+	lat := latencySummary(sess.Latency)
+	out := types.SummaryResp{
 		SessionID:      sess.ID,
-		LatencyP50Ms:   sess.LatencyP50,
+		LatencyP50Ms:   lat.FrameToTip.P50,
+		Latency:        lat,
 		FramesAnalyzed: sess.Frames,
 		Tips:           sess.Tips,
-	}, true
+		Usage:          sess.Usage,
+		Timeline:       sess.Timeline,
+		Skipped:        sess.Skipped,
+	}
+	if len(sess.Suppressed) > 0 {
+		out.Suppressed = map[string]int{}
+		for _, t := range sess.Suppressed {
+			out.Suppressed[t.Reason]++
+		}
+	}
+	if sess.Ended() {
+		out.EndedAt = sess.EndedAt.UnixMilli()
+		out.EndReason = sess.EndReason
+	}
+	return out, true
 }
@@ -0,0 +1,79 @@
+package tips
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	fail := errors.New("fail")
+	b := newBreaker(2, time.Minute)
+
+	b.record(fail, now)
+	if !b.allow(now) {
+		t.Fatal("open after 1 of 2 failures")
+	}
+	b.record(nil, now)
+	b.record(fail, now)
+	if !b.allow(now) {
+		t.Fatal("success did not reset the failure count")
+	}
+	b.record(fail, now)
+	if b.allow(now.Add(59 * time.Second)) {
+		t.Fatal("closed during cooldown")
+	}
+
+	// After the cooldown one trial call is let through at a time.
+	now = now.Add(time.Minute)
+	if !b.allow(now) {
+		t.Fatal("no trial after cooldown")
+	}
+	if b.allow(now) {
+		t.Fatal("second call allowed during trial")
+	}
+	b.record(fail, now)
+	if b.allow(now.Add(time.Second)) {
+		t.Fatal("failed trial did not reopen")
+	}
+
+	now = now.Add(time.Minute)
+	if !b.allow(now) {
+		t.Fatal("no trial after second cooldown")
+	}
+	b.record(nil, now)
+	if !b.allow(now) || !b.allow(now) {
+		t.Fatal("successful trial did not close")
+	}
+}
+
+func TestBreakerRelease(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	b := newBreaker(1, time.Minute)
+	b.record(errors.New("fail"), now)
+
+	now = now.Add(time.Minute)
+	if !b.allow(now) {
+		t.Fatal("no trial after cooldown")
+	}
+	// A canceled trial neither closes nor reopens the breaker.
+	b.release()
+	if !b.allow(now) {
+		t.Fatal("release did not end the trial")
+	}
+	if b.allow(now) {
+		t.Fatal("released trial closed the breaker")
+	}
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	now := time.Now()
+	b := newBreaker(0, time.Minute)
+	for range 5 {
+		b.record(errors.New("fail"), now)
+	}
+	if !b.allow(now) {
+		t.Fatal("disabled breaker opened")
+	}
+}
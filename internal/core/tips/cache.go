@@ -0,0 +1,182 @@
+package tips
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steveyiyo/hackyou-backend/internal/core/vision"
+	"github.com/steveyiyo/hackyou-backend/pkg/types"
+)
+
+// Versioned is implemented by providers whose tips depend on a prompt;
+// Cache keeps tips made with different prompt versions apart.
+type Versioned interface {
+	PromptVersion(mode, locale string) string
+}
+
+// CacheConfig configures a Cache. Zero fields take DefaultCacheConfig's.
+type CacheConfig struct {
+	// Size is the most tips kept; the least recently used go first.
+	Size int
+	// TTL is how long a tip is served from the cache.
+	TTL time.Duration
+}
+
+var DefaultCacheConfig = CacheConfig{
+	Size: 512,
+	TTL:  10 * time.Minute,
+}
+
+type cacheEntry struct {
+	key string
+	tip types.Tip
+	raw string
+	exp time.Time
+}
+
+// Cache is a Provider that answers frames that look like one seen before,
+// in the same mode, locale and prompt version, with the tip given then, and
+// asks the wrapped provider otherwise. Frames are compared by vision.DHash,
+// taken from the context when the caller has it. Being shared across
+// sessions, a cached tip carries no judgement about the session: its
+// PrevFollowed is cleared, tips saying the shot is ready are never cached,
+// and a tip repeating one in the session's history is asked for afresh.
+// Engine reports tips served from the cache as SourceCache. It is safe for
+// concurrent use.
+type Cache struct {
+	p   Provider
+	cfg CacheConfig
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	stats   types.CacheMetrics
+}
+
+func NewCache(p Provider, cfg CacheConfig) *Cache {
+	d := DefaultCacheConfig
+	if cfg.Size <= 0 {
+		cfg.Size = d.Size
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = d.TTL
+	}
+	return &Cache{p: p, cfg: cfg, entries: map[string]*list.Element{}, lru: list.New()}
+}
+
+func (c *Cache) TipFromImage(ctx context.Context, img []byte, mime string) (*types.Tip, string, error) {
+	key, ok := c.key(ctx, img, mime)
+	if !ok {
+		return c.p.TipFromImage(ctx, img, mime)
+	}
+	if tip, raw, ok := c.get(key, SessionFrom(ctx).History, time.Now()); ok {
+		markCached(ctx)
+		return tip, raw, nil
+	}
+	tip, raw, err := c.p.TipFromImage(ctx, img, mime)
+	if err == nil && tip != nil && !IsReadyTip(*tip) {
+		c.put(key, *tip, raw, time.Now())
+	}
+	return tip, raw, err
+}
+
+// key identifies the frame and prompt. Without a hash in ctx the frame is
+// decoded to hash it; frames that do not decode are not cached, the
+// provider reports them.
+func (c *Cache) key(ctx context.Context, img []byte, mime string) (string, bool) {
+	hash, ok := frameHashFrom(ctx)
+	if !ok {
+		im, _, err := vision.Decode(img, mime)
+		if err != nil {
+			return "", false
+		}
+		hash = vision.DHash(im)
+	}
+	sess := SessionFrom(ctx)
+	version := ""
+	if v, ok := c.p.(Versioned); ok {
+		version = v.PromptVersion(sess.Mode, sess.Locale)
+	}
+	return fmt.Sprintf("%016x|%s|%s|%s", hash, strings.ToLower(sess.Mode), sess.Locale, version), true
+}
+
+// repeats reports whether text is a near-duplicate of a tip in h, which
+// the prompt would have told the model not to give again.
+func repeats(text string, h []Turn) bool {
+	grams := bigrams(text)
+	for _, t := range h {
+		if similarity(grams, bigrams(t.Text)) >= DefaultSchedule.Similarity {
+			return true
+		}
+	}
+	return false
+}
+
+// get returns the tip cached under key unless it expired or repeats a tip
+// in hist; either counts as a miss.
+func (c *Cache) get(key string, hist []Turn, now time.Time) (*types.Tip, string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, "", false
+	}
+	e := el.Value.(*cacheEntry)
+	if !now.Before(e.exp) {
+		c.remove(el)
+		c.stats.Expired++
+		c.stats.Misses++
+		return nil, "", false
+	}
+	if repeats(e.tip.Text, hist) {
+		c.stats.Misses++
+		return nil, "", false
+	}
+	c.lru.MoveToFront(el)
+	c.stats.Hits++
+	// A copy with T unset, so the engine stamps it as a new tip.
+	tip := e.tip
+	tip.T = 0
+	tip.PrevFollowed = nil
+	return &tip, e.raw, true
+}
+
+func (c *Cache) put(key string, tip types.Tip, raw string, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := &cacheEntry{key: key, tip: tip, raw: raw, exp: now.Add(c.cfg.TTL)}
+	if el, ok := c.entries[key]; ok {
+		el.Value = e
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(e)
+	for c.lru.Len() > c.cfg.Size {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+}
+
+func (c *Cache) remove(el *list.Element) {
+	c.lru.Remove(el)
+	delete(c.entries, el.Value.(*cacheEntry).key)
+}
+
+// Metrics reports the cache's size and hit and miss counts since start.
+func (c *Cache) Metrics() types.CacheMetrics {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	m := c.stats
+	m.Size = c.lru.Len()
+	m.Capacity = c.cfg.Size
+	m.TTLSec = int64(c.cfg.TTL.Seconds())
+	if n := m.Hits + m.Misses; n > 0 {
+		m.HitRate = float64(m.Hits) / float64(n)
+	}
+	return m
+}
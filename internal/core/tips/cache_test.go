@@ -0,0 +1,160 @@
+package tips
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/steveyiyo/hackyou-backend/pkg/types"
+)
+
+// versionedProvider is a fakeProvider whose prompt version is version.
+type versionedProvider struct {
+	fakeProvider
+	version string
+}
+
+func (p *versionedProvider) PromptVersion(mode, locale string) string { return p.version }
+
+func frameCtx(hash uint64, s Session) context.Context {
+	return WithFrameHash(WithSession(context.Background(), s), hash)
+}
+
+func TestCacheKeys(t *testing.T) {
+	p := &versionedProvider{fakeProvider: fakeProvider{tip: &types.Tip{Text: "Move closer", PrevFollowed: new(bool)}}, version: "v1"}
+	c := NewCache(p, CacheConfig{})
+	portrait := Session{Mode: "portrait", Locale: "en"}
+	// Other sessions share tips: the history is not part of the key.
+	other := Session{Mode: "Portrait", Locale: "en", History: []Turn{{Text: "Too dark, find more light"}}}
+
+	calls := []struct {
+		name   string
+		ctx    context.Context
+		called bool
+	}{
+		{"first", frameCtx(1, portrait), true},
+		{"same frame", frameCtx(1, portrait), false},
+		{"other session", frameCtx(1, other), false},
+		{"other frame", frameCtx(2, portrait), true},
+		{"other mode", frameCtx(1, Session{Mode: "landscape", Locale: "en"}), true},
+		{"other locale", frameCtx(1, Session{Mode: "portrait", Locale: "ja"}), true},
+	}
+	for _, tt := range calls {
+		before := p.calls
+		tip, raw, err := c.TipFromImage(tt.ctx, anyFrame, "image/jpeg")
+		if err != nil || tip.Text != "Move closer" || raw != "raw" {
+			t.Fatalf("%s: TipFromImage = %v, %q, %v", tt.name, tip, raw, err)
+		}
+		if called := p.calls > before; called != tt.called {
+			t.Errorf("%s: provider called %v, want %v", tt.name, called, tt.called)
+		}
+		if !tt.called && (tip.PrevFollowed != nil || tip.T != 0) {
+			t.Errorf("%s: cached tip keeps session state: %+v", tt.name, *tip)
+		}
+	}
+
+	p.version = "v2"
+	before := p.calls
+	if _, _, err := c.TipFromImage(frameCtx(1, portrait), anyFrame, "image/jpeg"); err != nil || p.calls == before {
+		t.Error("new prompt version served from the cache")
+	}
+}
+
+func TestCacheSkipsRepeats(t *testing.T) {
+	p := &fakeProvider{tip: &types.Tip{Text: "Move closer"}}
+	c := NewCache(p, CacheConfig{})
+	sess := Session{Mode: "portrait", Locale: "en"}
+	c.TipFromImage(frameCtx(1, sess), anyFrame, "image/jpeg")
+
+	// The session already got this tip, so the model is asked for another.
+	sess.History = []Turn{{Text: "Move closer."}}
+	p.tip = &types.Tip{Text: "Raise the camera a little"}
+	tip, _, _ := c.TipFromImage(frameCtx(1, sess), anyFrame, "image/jpeg")
+	if p.calls != 2 || tip.Text != "Raise the camera a little" {
+		t.Fatalf("repeated tip served: %q after %d calls", tip.Text, p.calls)
+	}
+	tip, _, _ = c.TipFromImage(frameCtx(1, sess), anyFrame, "image/jpeg")
+	if p.calls != 2 || tip.Text != "Raise the camera a little" {
+		t.Errorf("fresh tip not cached: %q after %d calls", tip.Text, p.calls)
+	}
+}
+
+func TestCacheSkipsReadyTips(t *testing.T) {
+	p := &fakeProvider{tip: &types.Tip{Text: "Hold it", Ready: true}}
+	c := NewCache(p, CacheConfig{})
+	for range 2 {
+		c.TipFromImage(frameCtx(1, Session{}), anyFrame, "image/jpeg")
+	}
+	if p.calls != 2 {
+		t.Errorf("ready tip cached: %d calls", p.calls)
+	}
+}
+
+func TestCacheHashesUndecodedFrames(t *testing.T) {
+	p := &fakeProvider{tip: &types.Tip{Text: "Move closer"}}
+	c := NewCache(p, CacheConfig{})
+	ctx := WithSession(context.Background(), Session{Mode: "portrait"})
+	img := scene(t, 0, 200, 60, 0)
+	c.TipFromImage(ctx, img, "image/png")
+	c.TipFromImage(ctx, img, "image/png")
+	if p.calls != 1 {
+		t.Errorf("decoded frame not cached: %d calls", p.calls)
+	}
+	c.TipFromImage(ctx, anyFrame, "image/jpeg")
+	c.TipFromImage(ctx, anyFrame, "image/jpeg")
+	if p.calls != 3 {
+		t.Errorf("undecodable frame cached: %d calls", p.calls)
+	}
+}
+
+func TestCacheEvictionAndExpiry(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	c := NewCache(&fakeProvider{}, CacheConfig{Size: 2, TTL: time.Minute})
+	c.put("a", types.Tip{Text: "a"}, "", now)
+	c.put("b", types.Tip{Text: "b"}, "", now)
+	if _, _, ok := c.get("a", nil, now); !ok {
+		t.Fatal("a missing")
+	}
+	// b is now the least recently used.
+	c.put("c", types.Tip{Text: "c"}, "", now)
+	if _, _, ok := c.get("b", nil, now); ok {
+		t.Error("least recently used entry kept")
+	}
+	if _, _, ok := c.get("a", nil, now); !ok {
+		t.Error("recently used entry evicted")
+	}
+	if tip, _, ok := c.get("c", nil, now.Add(59*time.Second)); !ok || tip.Text != "c" {
+		t.Error("entry expired early")
+	}
+	if _, _, ok := c.get("c", nil, now.Add(time.Minute)); ok {
+		t.Error("entry served after its TTL")
+	}
+	// Replacing an entry restarts its TTL.
+	c.put("a", types.Tip{Text: "a2"}, "", now.Add(30*time.Second))
+	if tip, _, ok := c.get("a", nil, now.Add(80*time.Second)); !ok || tip.Text != "a2" {
+		t.Error("replaced entry not served")
+	}
+
+	got := c.Metrics()
+	want := types.CacheMetrics{
+		Size: 1, Capacity: 2, TTLSec: 60,
+		Hits: 4, Misses: 2, Expired: 1, Evictions: 1,
+		HitRate: 4.0 / 6,
+	}
+	if got != want {
+		t.Errorf("Metrics = %+v, want %+v", got, want)
+	}
+}
+
+func TestEngineReportsCacheHits(t *testing.T) {
+	p := &fakeProvider{tip: &types.Tip{Text: "Move closer"}}
+	e := New(Step{Name: "gemini", Provider: NewCache(p, CacheConfig{})})
+	ctx := frameCtx(1, Session{})
+	if d := e.Decide(ctx, anyFrame, "image/jpeg"); d.Source != "gemini" {
+		t.Errorf("miss reported as %q", d.Source)
+	}
+	d := e.Decide(ctx, anyFrame, "image/jpeg")
+	if d.Source != SourceCache || d.Tip.Reason != "gemini" || d.Tip.T == 0 {
+		t.Errorf("hit reported as %+v", d)
+	}
+}
@@ -2,10 +2,11 @@ package tips
 
 import (
 	"context"
-	"os"
+	"errors"
+	"fmt"
 	"time"
 
-	"github.com/steveyiyo/hackyou-backend/internal/core/gemini"
+	"github.com/steveyiyo/hackyou-backend/internal/core/i18n"
 	"github.com/steveyiyo/hackyou-backend/pkg/types"
 )
 
@@ -13,29 +14,75 @@ type Provider interface {
 	TipFromImage(ctx context.Context, img []byte, mime string) (*types.Tip, string, error)
 }
 
+// SourceStub is reported when no provider produced a tip.
+const SourceStub = "stub"
+
+// SourceCache is reported for a tip a Cache served without calling its
+// provider.
+const SourceCache = "cache"
+
+var (
+	errNoTip = errors.New("provider returned no tip")
+	// ErrCircuitOpen is reported for a provider skipped by its breaker.
+	ErrCircuitOpen = errors.New("circuit open")
+)
+
+// Step is one entry of the provider chain.
+type Step struct {
+	Name     string
+	Provider Provider
+	// Timeout bounds a single call; zero means no extra deadline.
+	Timeout time.Duration
+	// Failures consecutive errors open the circuit for Cooldown. Zero
+	// Failures disables the breaker.
+	Failures int
+	Cooldown time.Duration
+	// Metered providers are billed per call and are skipped by
+	// DecideLocal.
+	Metered bool
+	// Local providers need no network. They ignore the caller's deadline
+	// and run under Timeout alone, so a remote provider that used up the
+	// caller's time still leaves them time to answer.
+	Local bool
+}
+
+type link struct {
+	Step
+	br *breaker
+}
+
+// Engine asks each provider in order for a tip and returns the first one
+// that succeeds, falling back to a fixed stub tip.
 type Engine struct {
-	P Provider
+	chain []*link
+	// Messages translates the stub tip; nil uses the built-in catalog.
+	Messages *i18n.Catalog
 }
 
-func New() *Engine {
-	var p Provider
-	if k := os.Getenv("GOOGLE_API_KEY"); k != "" {
-		m := os.Getenv("GEMINI_MODEL")
-		if m == "" {
-			m = "gemini-2.5-flash"
-		}
-		if g, err := gemini.New(k, m); err == nil {
-			p = g
-		}
+// Decision is the outcome of Engine.Decide. Err is the failure of the
+// first provider that was tried and did not produce the tip, if any; it
+// explains why the tip is degraded.
+type Decision struct {
+	Tip    *types.Tip
+	Source string
+	Raw    string
+	Err    error
+}
+
+func New(steps ...Step) *Engine {
+	e := &Engine{}
+	for _, s := range steps {
+		e.chain = append(e.chain, &link{Step: s, br: newBreaker(s.Failures, s.Cooldown)})
 	}
-	return &Engine{P: p}
+	return e
 }
 
-func (e *Engine) DecideTip() *types.Tip {
+// DecideTip returns the stub tip in locale.
+func (e *Engine) DecideTip(locale string) *types.Tip {
 	return &types.Tip{
 		T:        time.Now().UnixMilli(),
-		Text:     "Gemini API 連線中 ...",
-		Priority: "high",
+		Text:     catalog(e.Messages).Printer(locale).Text(i18n.MsgConnecting),
+		Priority: types.PriorityNormal,
 		Yaw:      -3,
 		Pitch:    6,
 		Roll:     0,
@@ -43,20 +90,87 @@ func (e *Engine) DecideTip() *types.Tip {
 	}
 }
 
-func (e *Engine) DecideTipFromFrame(ctx context.Context, img []byte, mime string) *types.Tip {
-	if e.P != nil && len(img) > 0 && mime != "" {
-		if tip, _, err := e.P.TipFromImage(ctx, img, mime); err == nil && tip != nil {
+// Decide returns a tip for the frame. Providers are skipped while their
+// circuit is open; without a frame only the stub is used.
+func (e *Engine) Decide(ctx context.Context, img []byte, mime string) Decision {
+	return e.decide(ctx, img, mime, true)
+}
+
+// DecideLocal is Decide without metered providers, for sessions that ran
+// out of token budget.
+func (e *Engine) DecideLocal(ctx context.Context, img []byte, mime string) Decision {
+	return e.decide(ctx, img, mime, false)
+}
+
+func (e *Engine) decide(ctx context.Context, img []byte, mime string, metered bool) Decision {
+	var first error
+	if len(img) > 0 && mime != "" {
+		for _, l := range e.chain {
+			if l.Metered && !metered {
+				continue
+			}
+			if !l.br.allow(time.Now()) {
+				if first == nil {
+					first = fmt.Errorf("%s: %w", l.Name, ErrCircuitOpen)
+				}
+				continue
+			}
+			cctx := ctx
+			if l.Local {
+				cctx = context.WithoutCancel(ctx)
+			}
+			var cached bool
+			tip, raw, err := l.call(context.WithValue(cctx, cachedKey{}, &cached), img, mime)
+			// The caller giving up is not the provider's failure.
+			if err != nil && !l.Local && ctx.Err() != nil {
+				l.br.release()
+			} else {
+				l.br.record(err, time.Now())
+			}
+			if err != nil {
+				if first == nil {
+					first = fmt.Errorf("%s: %w", l.Name, err)
+				}
+				continue
+			}
 			if tip.T == 0 {
 				tip.T = time.Now().UnixMilli()
 			}
 			if tip.Priority == "" {
-				tip.Priority = "high"
+				tip.Priority = types.PriorityHigh
 			}
 			if tip.Reason == "" {
-				tip.Reason = "gemini"
+				tip.Reason = l.Name
 			}
-			return tip
+			source := l.Name
+			if cached {
+				source = SourceCache
+			}
+			return Decision{Tip: tip, Source: source, Raw: raw, Err: first}
 		}
 	}
-	return e.DecideTip()
+	return Decision{Tip: e.DecideTip(SessionFrom(ctx).Locale), Source: SourceStub, Err: first}
+}
+
+type cachedKey struct{}
+
+// markCached tells Engine that the tip being returned for ctx came from a
+// Cache.
+func markCached(ctx context.Context) {
+	if p, ok := ctx.Value(cachedKey{}).(*bool); ok {
+		*p = true
+	}
+}
+
+func (l *link) call(ctx context.Context, img []byte, mime string) (*types.Tip, string, error) {
+	if l.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.Timeout)
+		defer cancel()
+	}
+	tip, raw, err := l.Provider.TipFromImage(ctx, img, mime)
+	if err == nil && tip == nil {
+		err = errNoTip
+	}
+	return tip, raw, err
 }
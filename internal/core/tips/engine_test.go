@@ -0,0 +1,130 @@
+package tips
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/steveyiyo/hackyou-backend/pkg/types"
+)
+
+// fakeProvider answers with tip and err. With block set it waits for its
+// context to end instead.
+type fakeProvider struct {
+	tip   *types.Tip
+	err   error
+	block bool
+	calls int
+}
+
+func (p *fakeProvider) TipFromImage(ctx context.Context, img []byte, mime string) (*types.Tip, string, error) {
+	p.calls++
+	if p.block {
+		<-ctx.Done()
+		return nil, "", ctx.Err()
+	}
+	if ctx.Err() != nil {
+		return nil, "", ctx.Err()
+	}
+	if p.tip == nil {
+		return nil, "", p.err
+	}
+	t := *p.tip
+	return &t, "raw", p.err
+}
+
+var anyFrame = []byte("frame")
+
+func TestEngineFirstSuccess(t *testing.T) {
+	bad := &fakeProvider{err: errors.New("boom")}
+	good := &fakeProvider{tip: &types.Tip{Text: "Step back."}}
+	never := &fakeProvider{tip: &types.Tip{Text: "unused"}}
+	e := New(Step{Name: "bad", Provider: bad}, Step{Name: "good", Provider: good}, Step{Name: "never", Provider: never})
+
+	d := e.Decide(context.Background(), anyFrame, "image/jpeg")
+	if d.Source != "good" || d.Tip.Text != "Step back." || d.Raw != "raw" {
+		t.Fatalf("Decide = %+v", d)
+	}
+	if d.Tip.T == 0 || d.Tip.Priority != types.PriorityHigh || d.Tip.Reason != "good" {
+		t.Errorf("defaults not filled in: %+v", *d.Tip)
+	}
+	if d.Err == nil || d.Err.Error() != "bad: boom" {
+		t.Errorf("Err = %v, want the first failure", d.Err)
+	}
+	if never.calls != 0 {
+		t.Error("provider after the first success was called")
+	}
+}
+
+func TestEngineStub(t *testing.T) {
+	p := &fakeProvider{}
+	e := New(Step{Name: "nil", Provider: p})
+
+	d := e.Decide(context.Background(), nil, "")
+	if d.Source != SourceStub || p.calls != 0 {
+		t.Errorf("without a frame: source %s, %d calls", d.Source, p.calls)
+	}
+	d = e.Decide(context.Background(), anyFrame, "image/jpeg")
+	if d.Source != SourceStub || !errors.Is(d.Err, errNoTip) {
+		t.Errorf("nil tip: source %s, err %v", d.Source, d.Err)
+	}
+}
+
+func TestEngineTimeout(t *testing.T) {
+	slow := &fakeProvider{block: true}
+	e := New(Step{Name: "slow", Provider: slow, Timeout: 10 * time.Millisecond})
+	d := e.Decide(context.Background(), anyFrame, "image/jpeg")
+	if d.Source != SourceStub || !errors.Is(d.Err, context.DeadlineExceeded) {
+		t.Errorf("Decide = %s %v", d.Source, d.Err)
+	}
+}
+
+func TestEngineCircuitOpens(t *testing.T) {
+	bad := &fakeProvider{err: errors.New("boom")}
+	e := New(Step{Name: "bad", Provider: bad, Failures: 2, Cooldown: time.Minute})
+	for range 2 {
+		e.Decide(context.Background(), anyFrame, "image/jpeg")
+	}
+	d := e.Decide(context.Background(), anyFrame, "image/jpeg")
+	if !errors.Is(d.Err, ErrCircuitOpen) || bad.calls != 2 {
+		t.Errorf("Err = %v after %d calls, want an open circuit after 2", d.Err, bad.calls)
+	}
+}
+
+func TestEngineDecideLocalSkipsMetered(t *testing.T) {
+	remote := &fakeProvider{tip: &types.Tip{Text: "remote"}}
+	local := &fakeProvider{tip: &types.Tip{Text: "local"}}
+	e := New(Step{Name: "remote", Provider: remote, Metered: true}, Step{Name: "local", Provider: local, Local: true})
+
+	if d := e.DecideLocal(context.Background(), anyFrame, "image/jpeg"); d.Source != "local" || remote.calls != 0 {
+		t.Errorf("DecideLocal = %s with %d metered calls", d.Source, remote.calls)
+	}
+	if d := e.Decide(context.Background(), anyFrame, "image/jpeg"); d.Source != "remote" {
+		t.Errorf("Decide = %s", d.Source)
+	}
+}
+
+func TestEngineCallerDeadline(t *testing.T) {
+	remote := &fakeProvider{block: true}
+	local := &fakeProvider{tip: &types.Tip{Text: "local"}}
+	e := New(
+		Step{Name: "remote", Provider: remote, Failures: 1, Cooldown: time.Minute},
+		Step{Name: "local", Provider: local, Timeout: time.Second, Local: true},
+	)
+	for range 3 {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+		d := e.Decide(ctx, anyFrame, "image/jpeg")
+		cancel()
+		// The local provider still answers after the caller's deadline.
+		if d.Source != "local" {
+			t.Fatalf("Decide = %s %v", d.Source, d.Err)
+		}
+		if errors.Is(d.Err, ErrCircuitOpen) {
+			t.Fatal("the caller's deadline opened the remote provider's circuit")
+		}
+	}
+	if remote.calls != 3 {
+		t.Errorf("remote called %d times, want 3", remote.calls)
+	}
+}
@@ -0,0 +1,93 @@
+package tips
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"time"
+
+	"github.com/steveyiyo/hackyou-backend/internal/core/i18n"
+	"github.com/steveyiyo/hackyou-backend/internal/core/vision"
+	"github.com/steveyiyo/hackyou-backend/pkg/types"
+)
+
+// SourceHeuristic names the local provider in the chain.
+const SourceHeuristic = "heuristic"
+
+// Reason codes produced by Heuristic.
+const (
+	ReasonBlurry       = "blurry"
+	ReasonUnderexposed = "underexposed"
+	ReasonOverexposed  = "overexposed"
+	ReasonHighlights   = "blown_highlights"
+	ReasonShadows      = "crushed_shadows"
+	ReasonTilted       = "tilted"
+	ReasonGood         = "good"
+)
+
+// Heuristic thresholds, tuned on 320px-wide luma.
+const (
+	minSharpness    = 60
+	minBrightness   = 70
+	maxBrightness   = 190
+	maxHighlights   = 0.08
+	maxShadows      = 0.20
+	minTilt         = 2.0
+	minTiltConfider = 0.3
+)
+
+// Heuristic is a Provider that needs no network: it decodes the frame and
+// checks sharpness, exposure and horizon tilt, in that order of priority.
+// Tips are written in the session's locale using Messages, or the
+// built-in catalog when nil.
+type Heuristic struct {
+	Messages *i18n.Catalog
+}
+
+func (h Heuristic) TipFromImage(ctx context.Context, img []byte, mime string) (*types.Tip, string, error) {
+	im, _, err := vision.Decode(img, mime)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+	m := vision.Analyze(im)
+	raw, _ := json.Marshal(m)
+	tip := HeuristicTip(m, catalog(h.Messages).Printer(SessionFrom(ctx).Locale))
+	tip.T = time.Now().UnixMilli()
+	return tip, string(raw), nil
+}
+
+// HeuristicTip turns frame metrics into the single most important tip,
+// written with p.
+func HeuristicTip(m vision.Metrics, p i18n.Printer) *types.Tip {
+	switch {
+	case m.Sharpness < minSharpness:
+		return &types.Tip{Text: p.Text(i18n.MsgBlurry), Priority: types.PriorityCritical, Reason: ReasonBlurry}
+	case m.Brightness < minBrightness:
+		return &types.Tip{Text: p.Text(i18n.MsgUnderexposed), Priority: types.PriorityCritical, Reason: ReasonUnderexposed}
+	case m.Brightness > maxBrightness:
+		return &types.Tip{Text: p.Text(i18n.MsgOverexposed), Priority: types.PriorityHigh, Reason: ReasonOverexposed}
+	case m.Highlights > maxHighlights:
+		return &types.Tip{Text: p.Text(i18n.MsgHighlights), Priority: types.PriorityNormal, Reason: ReasonHighlights}
+	case m.Shadows > maxShadows:
+		return &types.Tip{Text: p.Text(i18n.MsgShadows), Priority: types.PriorityNormal, Reason: ReasonShadows}
+	case math.Abs(m.Tilt) >= minTilt && m.TiltConfidence >= minTiltConfider:
+		// A horizon tilted counter-clockwise in the frame means the camera
+		// is rolled clockwise; Roll is the correction, counter-clockwise
+		// positive.
+		msg := i18n.MsgTiltedCCW
+		if m.Tilt < 0 {
+			msg = i18n.MsgTiltedCW
+		}
+		return &types.Tip{
+			Text:     p.Text(msg),
+			Priority: types.PriorityNormal,
+			Roll:     math.Round(m.Tilt*10) / 10,
+			Reason:   ReasonTilted,
+		}
+	default:
+		return &types.Tip{Text: p.Text(i18n.MsgGood), Priority: types.PriorityPraise, Reason: ReasonGood}
+	}
+}
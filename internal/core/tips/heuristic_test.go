@@ -0,0 +1,122 @@
+package tips
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"testing"
+
+	"github.com/steveyiyo/hackyou-backend/internal/core/i18n"
+	"github.com/steveyiyo/hackyou-backend/internal/core/vision"
+	"github.com/steveyiyo/hackyou-backend/pkg/types"
+)
+
+// scene encodes a 640×480 PNG with a horizon at angle deg, sky and ground
+// luma above and below it, and texture of amplitude noise; noise zero
+// gives a soft, blurry frame.
+func scene(t *testing.T, deg, sky, ground, noise float64) []byte {
+	t.Helper()
+	const w, h = 640, 480
+	im := image.NewGray(image.Rect(0, 0, w, h))
+	slope := math.Tan(deg * math.Pi / 180)
+	seed := uint32(1)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			seed = seed*1664525 + 1013904223
+			n := (float64(seed>>24)/255*2 - 1) * noise
+			v := ground
+			if float64(y) < h/2-slope*(float64(x)-w/2) {
+				v = sky
+			}
+			im.SetGray(x, y, color.Gray{uint8(min(max(v+n, 0), 255))})
+		}
+	}
+	var b bytes.Buffer
+	if err := png.Encode(&b, im); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestHeuristicTipFromImage(t *testing.T) {
+	p := i18n.Default().Printer("en")
+	tests := []struct {
+		name     string
+		img      []byte
+		reason   string
+		priority string
+		text     string
+	}{
+		{"blurry", scene(t, 0, 140, 120, 0), ReasonBlurry, types.PriorityCritical, p.Text(i18n.MsgBlurry)},
+		{"underexposed", scene(t, 0, 40, 40, 30), ReasonUnderexposed, types.PriorityCritical, p.Text(i18n.MsgUnderexposed)},
+		{"overexposed", scene(t, 0, 215, 215, 30), ReasonOverexposed, types.PriorityHigh, p.Text(i18n.MsgOverexposed)},
+		{"tilted ccw", scene(t, 6, 190, 80, 40), ReasonTilted, types.PriorityNormal, p.Text(i18n.MsgTiltedCCW)},
+		{"tilted cw", scene(t, -6, 190, 80, 40), ReasonTilted, types.PriorityNormal, p.Text(i18n.MsgTiltedCW)},
+		{"good", scene(t, 0, 190, 80, 40), ReasonGood, types.PriorityPraise, p.Text(i18n.MsgGood)},
+	}
+	h := Heuristic{}
+	for _, tt := range tests {
+		tip, raw, err := h.TipFromImage(context.Background(), tt.img, "image/png")
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if tip.Reason != tt.reason || tip.Priority != tt.priority || tip.Text != tt.text {
+			t.Errorf("%s: tip = %+v, raw %s", tt.name, *tip, raw)
+		}
+		if tip.T == 0 || raw == "" {
+			t.Errorf("%s: T %d, raw %q", tt.name, tip.T, raw)
+		}
+	}
+}
+
+func TestHeuristicTiltRoll(t *testing.T) {
+	for _, deg := range []float64{-6, 6} {
+		tip, _, err := Heuristic{}.TipFromImage(context.Background(), scene(t, deg, 190, 80, 40), "image/png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(tip.Roll-deg) > 1 {
+			t.Errorf("horizon at %v°: roll %v", deg, tip.Roll)
+		}
+	}
+}
+
+func TestHeuristicTipThresholds(t *testing.T) {
+	p := i18n.Default().Printer("en")
+	ok := vision.Metrics{Brightness: 128, Sharpness: 500}
+	tests := []struct {
+		name   string
+		edit   func(m *vision.Metrics)
+		reason string
+	}{
+		{"good", func(m *vision.Metrics) {}, ReasonGood},
+		{"sharpness at limit", func(m *vision.Metrics) { m.Sharpness = minSharpness }, ReasonGood},
+		{"blur wins over exposure", func(m *vision.Metrics) { m.Sharpness = 10; m.Brightness = 20 }, ReasonBlurry},
+		{"highlights", func(m *vision.Metrics) { m.Highlights = 0.1 }, ReasonHighlights},
+		{"shadows", func(m *vision.Metrics) { m.Shadows = 0.3 }, ReasonShadows},
+		{"small tilt", func(m *vision.Metrics) { m.Tilt = 1.5; m.TiltConfidence = 0.9 }, ReasonGood},
+		{"unsure tilt", func(m *vision.Metrics) { m.Tilt = 8; m.TiltConfidence = 0.1 }, ReasonGood},
+		{"tilt", func(m *vision.Metrics) { m.Tilt = -8; m.TiltConfidence = 0.5 }, ReasonTilted},
+	}
+	for _, tt := range tests {
+		m := ok
+		tt.edit(&m)
+		if got := HeuristicTip(m, p).Reason; got != tt.reason {
+			t.Errorf("%s: reason %s, want %s", tt.name, got, tt.reason)
+		}
+	}
+}
+
+func TestHeuristicLocale(t *testing.T) {
+	ctx := WithSession(context.Background(), Session{Locale: "ja"})
+	tip, _, err := Heuristic{}.TipFromImage(ctx, scene(t, 0, 140, 120, 0), "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := i18n.Default().Printer("ja").Text(i18n.MsgBlurry); tip.Text != want {
+		t.Errorf("text = %q, want %q", tip.Text, want)
+	}
+}
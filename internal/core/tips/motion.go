@@ -0,0 +1,62 @@
+package tips
+
+import (
+	"sync"
+	"time"
+
+	"github.com/steveyiyo/hackyou-backend/internal/core/vision"
+)
+
+// Motion configures a MotionDetector. Zero fields take DefaultMotion's.
+type Motion struct {
+	// Threshold is the largest frame hash distance, in bits, that counts
+	// as the same scene; negative disables the detector.
+	Threshold int
+	// MaxReuse is how long the analysis of an unchanged scene is reused
+	// before providers are asked again.
+	MaxReuse time.Duration
+}
+
+var DefaultMotion = Motion{
+	Threshold: 4,
+	MaxReuse:  10 * time.Second,
+}
+
+// MotionDetector tells whether a stream's latest frame still shows the
+// scene that was last analysed. It is safe for concurrent use.
+type MotionDetector struct {
+	cfg Motion
+
+	mu   sync.Mutex
+	hash uint64
+	at   time.Time
+}
+
+func NewMotionDetector(cfg Motion) *MotionDetector {
+	d := DefaultMotion
+	if cfg.Threshold == 0 {
+		cfg.Threshold = d.Threshold
+	}
+	if cfg.MaxReuse <= 0 {
+		cfg.MaxReuse = d.MaxReuse
+	}
+	return &MotionDetector{cfg: cfg}
+}
+
+// Unchanged reports whether a frame with hash h shows the last analysed
+// scene, analysed recently enough to reuse at now.
+func (d *MotionDetector) Unchanged(h uint64, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.cfg.Threshold < 0 || d.at.IsZero() || now.Sub(d.at) >= d.cfg.MaxReuse {
+		return false
+	}
+	return vision.HashDistance(h, d.hash) <= d.cfg.Threshold
+}
+
+// Analyzed records that the frame with hash h was analysed at now.
+func (d *MotionDetector) Analyzed(h uint64, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.hash, d.at = h, now
+}
@@ -0,0 +1,79 @@
+package tips
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steveyiyo/hackyou-backend/internal/core/prompts"
+	"github.com/steveyiyo/hackyou-backend/pkg/types"
+)
+
+// Readiness configures a ReadyDetector. Zero fields take DefaultReadiness's.
+type Readiness struct {
+	// StableTips consecutive praise tips count as ready without the model
+	// saying so.
+	StableTips int
+	// Cooldown is how long after firing the detector stays quiet.
+	Cooldown time.Duration
+}
+
+var DefaultReadiness = Readiness{
+	StableTips: 3,
+	Cooldown:   10 * time.Second,
+}
+
+// ReadyDetector watches the tips of one stream for the moment the shot is
+// ready to take. It is safe for concurrent use.
+type ReadyDetector struct {
+	cfg Readiness
+
+	mu     sync.Mutex
+	stable int
+	fired  time.Time
+}
+
+func NewReadyDetector(cfg Readiness) *ReadyDetector {
+	d := DefaultReadiness
+	if cfg.StableTips <= 0 {
+		cfg.StableTips = d.StableTips
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = d.Cooldown
+	}
+	return &ReadyDetector{cfg: cfg}
+}
+
+// IsReadyTip reports whether t is the model saying the shot is ready
+// rather than an instruction to show.
+func IsReadyTip(t types.Tip) bool {
+	return t.Ready || strings.TrimSpace(t.Text) == prompts.Ready
+}
+
+// Observe feeds the next tip and reports whether the shot became ready at
+// now, and why: types.ReadyModel or types.ReadyStable.
+func (d *ReadyDetector) Observe(t types.Tip, now time.Time) (bool, string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	trigger := ""
+	switch {
+	case IsReadyTip(t):
+		trigger = types.ReadyModel
+	case t.Priority == types.PriorityPraise:
+		d.stable++
+		if d.stable >= d.cfg.StableTips {
+			trigger = types.ReadyStable
+		}
+	default:
+		d.stable = 0
+	}
+	if trigger == "" {
+		return false, ""
+	}
+	if !d.fired.IsZero() && now.Sub(d.fired) < d.cfg.Cooldown {
+		return false, ""
+	}
+	d.fired = now
+	d.stable = 0
+	return true, trigger
+}
@@ -0,0 +1,177 @@
+package tips
+
+import (
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/steveyiyo/hackyou-backend/pkg/types"
+)
+
+// Reasons a Scheduler withholds a tip.
+const (
+	SuppressDuplicate     = "duplicate"
+	SuppressLowerPriority = "lower_priority"
+	SuppressPraiseLimit   = "praise_limit"
+	// SuppressReady is a ready tip within the ready detector's cooldown.
+	SuppressReady = "ready"
+)
+
+// Schedule configures a Scheduler. Zero fields take DefaultSchedule's.
+type Schedule struct {
+	// Cooldown is how long a sent text blocks near-duplicates of itself;
+	// critical tips repeat after half of it.
+	Cooldown time.Duration
+	// Similarity is the bigram overlap, 0 to 1, at which two texts count
+	// as duplicates.
+	Similarity float64
+	// Hold is how long a sent tip blocks tips of lower priority.
+	Hold time.Duration
+	// PraiseEvery is the minimum gap between praise tips.
+	PraiseEvery time.Duration
+}
+
+var DefaultSchedule = Schedule{
+	Cooldown:    10 * time.Second,
+	Similarity:  0.8,
+	Hold:        4 * time.Second,
+	PraiseEvery: 15 * time.Second,
+}
+
+type sentTip struct {
+	at    time.Time
+	rank  int
+	grams map[string]struct{}
+}
+
+// Scheduler decides which tips of one stream are worth sending. It is safe
+// for concurrent use.
+type Scheduler struct {
+	cfg Schedule
+
+	mu         sync.Mutex
+	sent       []sentTip
+	lastPraise time.Time
+}
+
+func NewScheduler(cfg Schedule) *Scheduler {
+	d := DefaultSchedule
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = d.Cooldown
+	}
+	if cfg.Similarity <= 0 || cfg.Similarity > 1 {
+		cfg.Similarity = d.Similarity
+	}
+	if cfg.Hold <= 0 {
+		cfg.Hold = d.Hold
+	}
+	if cfg.PraiseEvery <= 0 {
+		cfg.PraiseEvery = d.PraiseEvery
+	}
+	return &Scheduler{cfg: cfg}
+}
+
+// Rank orders priorities, 0 being the most urgent. Unknown priorities
+// rank as normal.
+func Rank(priority string) int {
+	switch priority {
+	case types.PriorityCritical:
+		return 0
+	case types.PriorityHigh:
+		return 1
+	case types.PriorityPraise:
+		return 3
+	default:
+		return 2
+	}
+}
+
+// Admit reports whether t should be sent at now and, if so, records it as
+// sent. Otherwise it returns the suppression reason.
+func (s *Scheduler) Admit(t types.Tip, now time.Time) (bool, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rank := Rank(t.Priority)
+	grams := bigrams(t.Text)
+
+	keep := s.sent[:0]
+	for _, p := range s.sent {
+		if now.Sub(p.at) < max(s.cfg.Cooldown, s.cfg.Hold) {
+			keep = append(keep, p)
+		}
+	}
+	s.sent = keep
+
+	cooldown := s.cfg.Cooldown
+	if rank == 0 {
+		cooldown /= 2
+	}
+	for _, p := range s.sent {
+		age := now.Sub(p.at)
+		if age < cooldown && similarity(grams, p.grams) >= s.cfg.Similarity {
+			return false, SuppressDuplicate
+		}
+	}
+	if n := len(s.sent); n > 0 {
+		if last := s.sent[n-1]; rank > last.rank && now.Sub(last.at) < s.cfg.Hold {
+			return false, SuppressLowerPriority
+		}
+	}
+	if t.Priority == types.PriorityPraise {
+		if !s.lastPraise.IsZero() && now.Sub(s.lastPraise) < s.cfg.PraiseEvery {
+			return false, SuppressPraiseLimit
+		}
+		s.lastPraise = now
+	}
+	s.record(rank, grams, now)
+	return true, ""
+}
+
+// Sent records t as sent regardless of the schedule, e.g. for a tip the
+// user asked for.
+func (s *Scheduler) Sent(t types.Tip, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t.Priority == types.PriorityPraise {
+		s.lastPraise = now
+	}
+	s.record(Rank(t.Priority), bigrams(t.Text), now)
+}
+
+func (s *Scheduler) record(rank int, grams map[string]struct{}, now time.Time) {
+	s.sent = append(s.sent, sentTip{at: now, rank: rank, grams: grams})
+}
+
+// bigrams returns the character bigrams of text with case, spacing and
+// punctuation removed, which compares texts in any script.
+func bigrams(text string) map[string]struct{} {
+	var rs []rune
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			rs = append(rs, r)
+		}
+	}
+	out := map[string]struct{}{}
+	if len(rs) == 1 {
+		out[string(rs)] = struct{}{}
+	}
+	for i := 0; i+1 < len(rs); i++ {
+		out[string(rs[i:i+2])] = struct{}{}
+	}
+	return out
+}
+
+// similarity is the Dice coefficient of two bigram sets.
+func similarity(a, b map[string]struct{}) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	n := 0
+	for g := range a {
+		if _, ok := b[g]; ok {
+			n++
+		}
+	}
+	return 2 * float64(n) / float64(len(a)+len(b))
+}
@@ -0,0 +1,129 @@
+package tips
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/steveyiyo/hackyou-backend/pkg/types"
+)
+
+var t0 = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+type step struct {
+	at       time.Duration
+	priority string
+	text     string
+	ok       bool
+	reason   string
+}
+
+func runSteps(t *testing.T, s *Scheduler, steps []step) {
+	t.Helper()
+	for i, st := range steps {
+		ok, reason := s.Admit(types.Tip{Text: st.text, Priority: st.priority}, t0.Add(st.at))
+		if ok != st.ok || reason != st.reason {
+			t.Errorf("step %d (%s %q at %v): got %v %q, want %v %q",
+				i, st.priority, st.text, st.at, ok, reason, st.ok, st.reason)
+		}
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"Hold steady", "hold, STEADY!", 1},
+		{"abcd", "abce", 2.0 / 3},
+		{"tilt left", "tilt right", 0.4},
+		{"往左一點", "往左一點。", 1},
+		{"", "abc", 0},
+		{"a", "a", 1},
+	}
+	for _, tt := range tests {
+		if got := similarity(bigrams(tt.a), bigrams(tt.b)); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("similarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSchedulerDuplicates(t *testing.T) {
+	normal := types.PriorityNormal
+	// "abcd" and "abce" overlap by 2/3.
+	runSteps(t, NewScheduler(Schedule{Similarity: 0.6}), []step{
+		{0, normal, "abcd", true, ""},
+		{time.Second, normal, "abce", false, SuppressDuplicate},
+	})
+	runSteps(t, NewScheduler(Schedule{Similarity: 0.7}), []step{
+		{0, normal, "abcd", true, ""},
+		{time.Second, normal, "abce", true, ""},
+	})
+	runSteps(t, NewScheduler(Schedule{Cooldown: 10 * time.Second}), []step{
+		{0, normal, "Move a little closer", true, ""},
+		{9 * time.Second, normal, "move a little closer.", false, SuppressDuplicate},
+		{10 * time.Second, normal, "Move a little closer", true, ""},
+	})
+}
+
+func TestSchedulerCriticalCooldown(t *testing.T) {
+	critical, normal := types.PriorityCritical, types.PriorityNormal
+	runSteps(t, NewScheduler(Schedule{Cooldown: 10 * time.Second, Hold: time.Second}), []step{
+		{0, critical, "Lens is blocked", true, ""},
+		{4 * time.Second, critical, "Lens is blocked", false, SuppressDuplicate},
+		{5 * time.Second, critical, "Lens is blocked", true, ""},
+		// Only critical tips get the shorter cooldown.
+		{6 * time.Second, normal, "Lower the camera", true, ""},
+		{11 * time.Second, normal, "Lower the camera", false, SuppressDuplicate},
+	})
+}
+
+func TestSchedulerHold(t *testing.T) {
+	critical, high, normal, praise := types.PriorityCritical, types.PriorityHigh, types.PriorityNormal, types.PriorityPraise
+	runSteps(t, NewScheduler(Schedule{Hold: 4 * time.Second}), []step{
+		{0, high, "Too dark, find more light", true, ""},
+		{3 * time.Second, normal, "Step back a little", false, SuppressLowerPriority},
+		{3 * time.Second, praise, "Nice colours", false, SuppressLowerPriority},
+		{3 * time.Second, high, "Horizon is tilted", true, ""},
+		{4 * time.Second, critical, "Lens is blocked", true, ""},
+		// The hold follows the last tip sent, now the critical one.
+		{7 * time.Second, high, "Hold the phone steady", false, SuppressLowerPriority},
+		{8 * time.Second, normal, "Step back a little", true, ""},
+	})
+}
+
+func TestSchedulerPraiseLimit(t *testing.T) {
+	praise := types.PriorityPraise
+	s := NewScheduler(Schedule{PraiseEvery: 15 * time.Second, Hold: time.Second})
+	runSteps(t, s, []step{
+		{0, praise, "Great shot", true, ""},
+		{10 * time.Second, praise, "Lovely light", false, SuppressPraiseLimit},
+		// A withheld praise does not restart the limit.
+		{15 * time.Second, praise, "Lovely light", true, ""},
+	})
+
+	s.Sent(types.Tip{Text: "Beautiful", Priority: praise}, t0.Add(40*time.Second))
+	runSteps(t, s, []step{
+		{50 * time.Second, praise, "Well framed", false, SuppressPraiseLimit},
+		{55 * time.Second, praise, "Well framed", true, ""},
+	})
+}
+
+func TestSchedulerPrunes(t *testing.T) {
+	normal := types.PriorityNormal
+	s := NewScheduler(Schedule{Cooldown: 10 * time.Second, Hold: 4 * time.Second})
+	runSteps(t, s, []step{
+		{0, normal, "Move left", true, ""},
+		{time.Second, normal, "Raise the camera", true, ""},
+		{2 * time.Second, normal, "Find more light", true, ""},
+		{11 * time.Second, normal, "Tap to focus", true, ""},
+	})
+	// Tips older than the longer of cooldown and hold are dropped.
+	if n := len(s.sent); n != 2 {
+		t.Fatalf("%d tips kept, want 2", n)
+	}
+	if got := s.sent[0].at; !got.Equal(t0.Add(2 * time.Second)) {
+		t.Errorf("oldest kept tip sent at %v", got.Sub(t0))
+	}
+	runSteps(t, s, []step{{12 * time.Second, normal, "Move left", true, ""}})
+}
@@ -0,0 +1,83 @@
+package tips
+
+import (
+	"context"
+	"time"
+
+	"github.com/steveyiyo/hackyou-backend/internal/core/i18n"
+	"github.com/steveyiyo/hackyou-backend/pkg/types"
+)
+
+// Session describes the session a tip is for, so providers can tailor
+// their coaching. Callers attach it to the context passed to Decide.
+type Session struct {
+	Mode    string
+	Locale  string
+	History []Turn
+}
+
+// Turn is a tip sent earlier in the session. Followed is nil when it is
+// not known whether the user followed it.
+type Turn struct {
+	Text     string
+	Reason   string
+	Age      time.Duration
+	Followed *bool
+}
+
+// History returns the last n of the session's tips, oldest first. A tip
+// counts as followed per the client's feedback or, without it, per the
+// model's judgement made with the next tip.
+func History(all []types.Tip, n int, now time.Time) []Turn {
+	if n <= 0 || len(all) == 0 {
+		return nil
+	}
+	start := max(len(all)-n, 0)
+	out := make([]Turn, 0, len(all)-start)
+	for i := start; i < len(all); i++ {
+		t := all[i]
+		f := t.Followed
+		if f == nil && i+1 < len(all) {
+			f = all[i+1].PrevFollowed
+		}
+		out = append(out, Turn{
+			Text:     t.Text,
+			Reason:   t.Reason,
+			Age:      now.Sub(time.UnixMilli(t.T)),
+			Followed: f,
+		})
+	}
+	return out
+}
+
+type sessionKey struct{}
+
+func WithSession(ctx context.Context, s Session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, s)
+}
+
+// SessionFrom returns the Session attached to ctx, or the zero Session.
+func SessionFrom(ctx context.Context) Session {
+	s, _ := ctx.Value(sessionKey{}).(Session)
+	return s
+}
+
+type frameHashKey struct{}
+
+// WithFrameHash attaches the vision.DHash of the frame being decided on,
+// so providers that compare frames need not decode it again.
+func WithFrameHash(ctx context.Context, hash uint64) context.Context {
+	return context.WithValue(ctx, frameHashKey{}, hash)
+}
+
+func frameHashFrom(ctx context.Context) (uint64, bool) {
+	h, ok := ctx.Value(frameHashKey{}).(uint64)
+	return h, ok
+}
+
+func catalog(c *i18n.Catalog) *i18n.Catalog {
+	if c != nil {
+		return c
+	}
+	return i18n.Default()
+}
@@ -0,0 +1,154 @@
+// Package usage accounts for model tokens: it carries token counts from a
+// provider call back to the caller, prices them, keeps per-user daily and
+// process-wide totals and enforces token budgets.
+package usage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/steveyiyo/hackyou-backend/pkg/types"
+)
+
+var (
+	ErrSessionBudget = errors.New("session token budget exceeded")
+	ErrDailyBudget   = errors.New("daily token budget exceeded")
+)
+
+// Tokens is what one model response was billed for.
+type Tokens struct {
+	Prompt int64
+	Output int64
+	Total  int64
+}
+
+// Price is the cost in USD per million prompt and output tokens.
+type Price struct {
+	InputPerMTok  float64
+	OutputPerMTok float64
+}
+
+func (p Price) cost(t Tokens) float64 {
+	return (float64(t.Prompt)*p.InputPerMTok + float64(t.Output)*p.OutputPerMTok) / 1e6
+}
+
+// Recorder receives the tokens of each model response made under a
+// context.
+type Recorder func(model string, t Tokens)
+
+type recorderKey struct{}
+
+// WithRecorder returns a context under which Record calls rec.
+func WithRecorder(ctx context.Context, rec Recorder) context.Context {
+	return context.WithValue(ctx, recorderKey{}, rec)
+}
+
+// Record reports t to the context's Recorder, if any.
+func Record(ctx context.Context, model string, t Tokens) {
+	if rec, ok := ctx.Value(recorderKey{}).(Recorder); ok {
+		rec(model, t)
+	}
+}
+
+// Meter keeps usage per owner for the current UTC day and for the whole
+// process. SessionBudget and DailyBudget are total-token limits; zero
+// disables a limit.
+type Meter struct {
+	Price         Price
+	SessionBudget int64
+	DailyBudget   int64
+
+	mu      sync.Mutex
+	since   time.Time
+	day     string
+	total   types.TokenUsage
+	byModel map[string]types.TokenUsage
+	owners  map[string]types.TokenUsage
+}
+
+func NewMeter(p Price) *Meter {
+	return &Meter{
+		Price:   p,
+		since:   time.Now(),
+		byModel: map[string]types.TokenUsage{},
+		owners:  map[string]types.TokenUsage{},
+	}
+}
+
+// Add accounts one call by owner and returns its priced usage.
+func (m *Meter) Add(owner, model string, t Tokens) types.TokenUsage {
+	u := types.TokenUsage{
+		Calls:        1,
+		PromptTokens: t.Prompt,
+		OutputTokens: t.Output,
+		TotalTokens:  t.Total,
+		CostUSD:      m.Price.cost(t),
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rollover(time.Now())
+	m.total.Add(u)
+	bm := m.byModel[model]
+	bm.Add(u)
+	m.byModel[model] = bm
+	o := m.owners[owner]
+	o.Add(u)
+	m.owners[owner] = o
+	return u
+}
+
+// Check returns ErrSessionBudget or ErrDailyBudget once the session's
+// usage or owner's usage today has reached its budget.
+func (m *Meter) Check(owner string, session types.TokenUsage) error {
+	if m.SessionBudget > 0 && session.TotalTokens >= m.SessionBudget {
+		return ErrSessionBudget
+	}
+	if m.DailyBudget > 0 {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.rollover(time.Now())
+		if m.owners[owner].TotalTokens >= m.DailyBudget {
+			return ErrDailyBudget
+		}
+	}
+	return nil
+}
+
+// Budgeted reports whether either budget is set.
+func (m *Meter) Budgeted() bool {
+	return m.SessionBudget > 0 || m.DailyBudget > 0
+}
+
+// Metrics returns process-wide usage and owner's usage today.
+func (m *Meter) Metrics(owner string) types.UsageMetricsResp {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rollover(time.Now())
+	byModel := make(map[string]types.TokenUsage, len(m.byModel))
+	for k, v := range m.byModel {
+		byModel[k] = v
+	}
+	return types.UsageMetricsResp{
+		Since:   m.since.UnixMilli(),
+		Total:   m.total,
+		ByModel: byModel,
+		Owner: types.OwnerUsage{
+			Owner:       owner,
+			Day:         m.day,
+			Usage:       m.owners[owner],
+			DailyBudget: m.DailyBudget,
+		},
+		SessionBudget: m.SessionBudget,
+	}
+}
+
+// rollover starts a new day of per-owner usage; m.mu must be held.
+func (m *Meter) rollover(now time.Time) {
+	day := now.UTC().Format(time.DateOnly)
+	if day != m.day {
+		m.day = day
+		clear(m.owners)
+	}
+}
@@ -0,0 +1,110 @@
+package vision
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"slices"
+
+	"golang.org/x/image/draw"
+)
+
+// FrameOptions control PrepareFrame. Zero fields take DefaultFrameOptions'.
+type FrameOptions struct {
+	// Types are the accepted content types.
+	Types []string
+	// MaxDim bounds the longer side of a prepared frame in pixels.
+	MaxDim int
+	// Quality is the JPEG quality, 1-100, of re-encoded frames.
+	Quality int
+}
+
+var DefaultFrameOptions = FrameOptions{
+	Types:   []string{"image/jpeg", "image/png", "image/webp"},
+	MaxDim:  1024,
+	Quality: 80,
+}
+
+// MaxPixels is the largest image, in pixels, CheckSize lets through: more
+// than a phone camera produces.
+const MaxPixels = 50_000_000
+
+var (
+	ErrTypeNotAllowed = errors.New("content type not allowed")
+	ErrTooLarge       = errors.New("image dimensions too large")
+)
+
+// Frame is a validated frame, ready to store and send to providers. Hash
+// is the DHash of its image.
+type Frame struct {
+	Data        []byte
+	ContentType string
+	Width       int
+	Height      int
+	Hash        uint64
+}
+
+// PrepareFrame checks that b is a well-formed image of an allowed type
+// matching mime and returns it at most o.MaxDim pixels on its longer side.
+// A frame that is already a JPEG of allowed size is kept as is; any other
+// is re-encoded as JPEG at o.Quality.
+func PrepareFrame(b []byte, mime string, o FrameOptions) (Frame, error) {
+	o = o.withDefaults()
+	if !slices.Contains(o.Types, mime) {
+		return Frame{}, fmt.Errorf("%w: %s", ErrTypeNotAllowed, mime)
+	}
+	cfg, err := CheckSize(b)
+	if err != nil {
+		return Frame{}, err
+	}
+	img, mime, err := Decode(b, mime)
+	if err != nil {
+		return Frame{}, err
+	}
+	w, h := cfg.Width, cfg.Height
+	hash := DHash(img)
+	if mime == "image/jpeg" && max(w, h) <= o.MaxDim {
+		return Frame{Data: b, ContentType: mime, Width: w, Height: h, Hash: hash}, nil
+	}
+	if long := max(w, h); long > o.MaxDim {
+		w = max(w*o.MaxDim/long, 1)
+		h = max(h*o.MaxDim/long, 1)
+		dst := image.NewRGBA(image.Rect(0, 0, w, h))
+		draw.ApproxBiLinear.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Src, nil)
+		img = dst
+	}
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, img, &jpeg.Options{Quality: o.Quality}); err != nil {
+		return Frame{}, err
+	}
+	return Frame{Data: out.Bytes(), ContentType: "image/jpeg", Width: w, Height: h, Hash: hash}, nil
+}
+
+// CheckSize reads the dimensions from the header of image b and rejects
+// images over MaxPixels, so that decoding it cannot exhaust memory.
+func CheckSize(b []byte) (image.Config, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return cfg, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > MaxPixels {
+		return cfg, fmt.Errorf("%w: %dx%d", ErrTooLarge, cfg.Width, cfg.Height)
+	}
+	return cfg, nil
+}
+
+func (o FrameOptions) withDefaults() FrameOptions {
+	d := DefaultFrameOptions
+	if len(o.Types) == 0 {
+		o.Types = d.Types
+	}
+	if o.MaxDim <= 0 {
+		o.MaxDim = d.MaxDim
+	}
+	if o.Quality <= 0 || o.Quality > 100 {
+		o.Quality = d.Quality
+	}
+	return o
+}
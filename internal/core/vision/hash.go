@@ -0,0 +1,40 @@
+package vision
+
+import (
+	"image"
+	"math/bits"
+)
+
+// DHash is a 64-bit difference hash of img: each bit tells whether a cell
+// of a 9x8 grid of mean luma is brighter than its right neighbour. Frames
+// that look alike have hashes a small Hamming distance apart, whatever
+// their size or encoding.
+func DHash(img image.Image) uint64 {
+	g := Luma(img, 9*8)
+	if g.W < 9 || g.H < 8 {
+		return 0
+	}
+	var cells [8][9]float64
+	var counts [8][9]int
+	for y := 0; y < g.H; y++ {
+		cy := y * 8 / g.H
+		for x := 0; x < g.W; x++ {
+			cx := x * 9 / g.W
+			cells[cy][cx] += g.at(x, y)
+			counts[cy][cx]++
+		}
+	}
+	var h uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			h <<= 1
+			if cells[y][x]/float64(counts[y][x]) > cells[y][x+1]/float64(counts[y][x+1]) {
+				h |= 1
+			}
+		}
+	}
+	return h
+}
+
+// HashDistance is the number of bits in which a and b differ.
+func HashDistance(a, b uint64) int { return bits.OnesCount64(a ^ b) }
@@ -0,0 +1,250 @@
+package vision
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"sort"
+
+	_ "golang.org/x/image/webp"
+)
+
+// analysisWidth is the width frames are sampled down to before analysis.
+const analysisWidth = 320
+
+var ErrUnsupported = errors.New("unsupported image type")
+
+// Decode decodes a JPEG, PNG or WebP image. mime, if set, must match the
+// detected format.
+func Decode(b []byte, mime string) (image.Image, string, error) {
+	img, format, err := image.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, "", err
+	}
+	got := "image/" + format
+	if mime != "" && mime != got {
+		return nil, "", fmt.Errorf("%w: content type %s but data is %s", ErrUnsupported, mime, got)
+	}
+	return img, got, nil
+}
+
+// Metrics describes the exposure, sharpness and level of a frame.
+type Metrics struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	// Brightness is the mean luma, 0-255.
+	Brightness float64 `json:"brightness"`
+	// Highlights and Shadows are the fractions of clipped pixels.
+	Highlights float64 `json:"highlights"`
+	Shadows    float64 `json:"shadows"`
+	// Histogram counts luma values of the analysed (downsampled) image.
+	Histogram [256]int `json:"-"`
+	// Sharpness is the variance of the Laplacian; low means blurry.
+	Sharpness float64 `json:"sharpness"`
+	// Tilt is the angle of the dominant near-horizontal line in degrees,
+	// counter-clockwise positive. TiltConfidence is in [0,1].
+	Tilt           float64 `json:"tilt_deg"`
+	TiltConfidence float64 `json:"tilt_confidence"`
+}
+
+// Gray is a row-major luma image.
+type Gray struct {
+	W, H int
+	Pix  []float64
+}
+
+func (g *Gray) at(x, y int) float64 { return g.Pix[y*g.W+x] }
+
+// Luma samples img down to at most width pixels wide and converts it to
+// luma (BT.601).
+func Luma(img image.Image, width int) *Gray {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > width {
+		h = h * width / w
+		w = width
+	}
+	if w < 1 || h < 1 {
+		return &Gray{}
+	}
+	g := &Gray{W: w, H: h, Pix: make([]float64, w*h)}
+	for y := 0; y < h; y++ {
+		sy := b.Min.Y + y*b.Dy()/h
+		for x := 0; x < w; x++ {
+			sx := b.Min.X + x*b.Dx()/w
+			r, gg, bb, _ := img.At(sx, sy).RGBA()
+			g.Pix[y*w+x] = (0.299*float64(r) + 0.587*float64(gg) + 0.114*float64(bb)) / 257
+		}
+	}
+	return g
+}
+
+func Analyze(img image.Image) Metrics {
+	b := img.Bounds()
+	m := Metrics{Width: b.Dx(), Height: b.Dy()}
+	g := Luma(img, analysisWidth)
+	n := len(g.Pix)
+	if n == 0 {
+		return m
+	}
+	var sum float64
+	var hi, lo int
+	for _, v := range g.Pix {
+		sum += v
+		i := int(math.Round(v))
+		i = min(max(i, 0), 255)
+		m.Histogram[i]++
+		if i >= 250 {
+			hi++
+		}
+		if i <= 5 {
+			lo++
+		}
+	}
+	m.Brightness = sum / float64(n)
+	m.Highlights = float64(hi) / float64(n)
+	m.Shadows = float64(lo) / float64(n)
+	m.Sharpness = laplacianVariance(g)
+	m.Tilt, m.TiltConfidence = horizonTilt(g)
+	return m
+}
+
+func laplacianVariance(g *Gray) float64 {
+	if g.W < 3 || g.H < 3 {
+		return 0
+	}
+	var sum, sq float64
+	var n int
+	for y := 1; y < g.H-1; y++ {
+		for x := 1; x < g.W-1; x++ {
+			l := g.at(x-1, y) + g.at(x+1, y) + g.at(x, y-1) + g.at(x, y+1) - 4*g.at(x, y)
+			sum += l
+			sq += l * l
+			n++
+		}
+	}
+	mean := sum / float64(n)
+	return sq/float64(n) - mean*mean
+}
+
+// maxTilt bounds the line angles considered part of a horizon.
+const maxTilt = 25
+
+// tiltBlock is the side of the square windows over which gradient
+// orientation is averaged; this recovers the direction of aliased,
+// staircase-like edges that a per-pixel gradient misreads.
+const tiltBlock = 8
+
+// horizonTilt estimates the dominant near-horizontal edge direction. For
+// each block it computes the structure tensor of the Sobel gradients and
+// votes with the block orientation, weighted by edge energy times
+// coherence, for the strongest quarter of blocks. It returns the refined
+// histogram peak and the share of votes around it.
+func horizonTilt(g *Gray) (float64, float64) {
+	if g.W < 2*tiltBlock || g.H < 2*tiltBlock {
+		return 0, 0
+	}
+	g = boxBlur(g)
+	type vote struct{ angle, weight float64 }
+	var votes []vote
+	for by := 1; by+tiltBlock < g.H; by += tiltBlock {
+		for bx := 1; bx+tiltBlock < g.W; bx += tiltBlock {
+			var jxx, jyy, jxy float64
+			for y := by; y < by+tiltBlock; y++ {
+				for x := bx; x < bx+tiltBlock; x++ {
+					gx := g.at(x+1, y-1) + 2*g.at(x+1, y) + g.at(x+1, y+1) -
+						g.at(x-1, y-1) - 2*g.at(x-1, y) - g.at(x-1, y+1)
+					gy := g.at(x-1, y+1) + 2*g.at(x, y+1) + g.at(x+1, y+1) -
+						g.at(x-1, y-1) - 2*g.at(x, y-1) - g.at(x+1, y-1)
+					jxx += gx * gx
+					jyy += gy * gy
+					jxy += gx * gy
+				}
+			}
+			energy := jxx + jyy
+			if energy == 0 {
+				continue
+			}
+			coherence := math.Hypot(jxx-jyy, 2*jxy) / energy
+			// Dominant gradient direction; the edge runs perpendicular to
+			// it. Image y grows downwards, so negate to get
+			// counter-clockwise angles.
+			theta := 0.5 * math.Atan2(2*jxy, jxx-jyy) * 180 / math.Pi
+			a := -(theta + 90)
+			for a > 90 {
+				a -= 180
+			}
+			for a <= -90 {
+				a += 180
+			}
+			votes = append(votes, vote{a, math.Sqrt(energy) * coherence})
+		}
+	}
+	if len(votes) == 0 {
+		return 0, 0
+	}
+	ws := make([]float64, len(votes))
+	for i, v := range votes {
+		ws[i] = v.weight
+	}
+	sort.Float64s(ws)
+	cut := ws[len(ws)*3/4]
+
+	const bins = 2*maxTilt + 1
+	var hist [bins]float64
+	var total float64
+	for _, v := range votes {
+		if v.weight < cut || v.weight == 0 {
+			continue
+		}
+		total += v.weight
+		if math.Abs(v.angle) > maxTilt {
+			continue
+		}
+		hist[int(math.Round(v.angle))+maxTilt] += v.weight
+	}
+	if total == 0 {
+		return 0, 0
+	}
+	peak := 0
+	for i := range hist {
+		if hist[i] > hist[peak] {
+			peak = i
+		}
+	}
+	var w, sum float64
+	for i := max(peak-2, 0); i <= min(peak+2, bins-1); i++ {
+		w += hist[i]
+		sum += hist[i] * float64(i-maxTilt)
+	}
+	if w == 0 {
+		return 0, 0
+	}
+	return sum / w, w / total
+}
+
+func boxBlur(g *Gray) *Gray {
+	out := &Gray{W: g.W, H: g.H, Pix: make([]float64, len(g.Pix))}
+	for y := 0; y < g.H; y++ {
+		for x := 0; x < g.W; x++ {
+			var sum float64
+			var n int
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					xx, yy := x+dx, y+dy
+					if xx < 0 || yy < 0 || xx >= g.W || yy >= g.H {
+						continue
+					}
+					sum += g.at(xx, yy)
+					n++
+				}
+			}
+			out.Pix[y*g.W+x] = sum / float64(n)
+		}
+	}
+	return out
+}
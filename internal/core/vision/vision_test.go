@@ -0,0 +1,89 @@
+package vision
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// synth draws a w×h grey image: a horizon through the centre at angle deg,
+// counter-clockwise positive, with luma sky above and ground below, plus
+// deterministic texture of amplitude noise.
+func synth(w, h int, deg, sky, ground, noise float64) *image.Gray {
+	im := image.NewGray(image.Rect(0, 0, w, h))
+	slope := math.Tan(deg * math.Pi / 180)
+	seed := uint32(1)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			seed = seed*1664525 + 1013904223
+			n := (float64(seed>>24)/255*2 - 1) * noise
+			v := ground
+			if float64(y) < float64(h)/2-slope*(float64(x)-float64(w)/2) {
+				v = sky
+			}
+			im.SetGray(x, y, color.Gray{uint8(min(max(v+n, 0), 255))})
+		}
+	}
+	return im
+}
+
+// ramp draws a smooth vertical gradient from top to bottom luma.
+func ramp(w, h int, top, bottom float64) *image.Gray {
+	im := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		v := top + (bottom-top)*float64(y)/float64(h-1)
+		for x := 0; x < w; x++ {
+			im.SetGray(x, y, color.Gray{uint8(v)})
+		}
+	}
+	return im
+}
+
+func TestAnalyzeExposure(t *testing.T) {
+	tests := []struct {
+		name           string
+		im             image.Image
+		lo, hi         float64
+		highs, shadows bool
+	}{
+		{"mid", synth(640, 480, 0, 128, 128, 60), 120, 136, false, false},
+		{"dark", synth(640, 480, 0, 40, 40, 30), 30, 50, false, false},
+		{"bright", synth(640, 480, 0, 215, 215, 30), 205, 225, false, false},
+		{"clipped sky", synth(640, 480, 0, 255, 110, 0), 175, 190, true, false},
+		{"crushed ground", synth(640, 480, 0, 150, 0, 0), 70, 80, false, true},
+	}
+	for _, tt := range tests {
+		m := Analyze(tt.im)
+		if m.Width != 640 || m.Height != 480 {
+			t.Errorf("%s: size %dx%d", tt.name, m.Width, m.Height)
+		}
+		if m.Brightness < tt.lo || m.Brightness > tt.hi {
+			t.Errorf("%s: brightness %.1f, want %v-%v", tt.name, m.Brightness, tt.lo, tt.hi)
+		}
+		if (m.Highlights > 0.4) != tt.highs || (m.Shadows > 0.4) != tt.shadows {
+			t.Errorf("%s: highlights %.2f, shadows %.2f", tt.name, m.Highlights, m.Shadows)
+		}
+	}
+}
+
+func TestAnalyzeSharpness(t *testing.T) {
+	sharp := Analyze(synth(640, 480, 0, 128, 128, 60)).Sharpness
+	soft := Analyze(ramp(640, 480, 90, 170)).Sharpness
+	if sharp < 1000 || soft > 10 {
+		t.Errorf("sharpness: textured %.1f, smooth %.1f", sharp, soft)
+	}
+}
+
+func TestHorizonTilt(t *testing.T) {
+	for _, deg := range []float64{-12, -6, 0, 6, 12} {
+		m := Analyze(synth(640, 480, deg, 190, 80, 40))
+		if math.Abs(m.Tilt-deg) > 1.5 || m.TiltConfidence < 0.3 {
+			t.Errorf("horizon at %v°: tilt %.2f, confidence %.2f", deg, m.Tilt, m.TiltConfidence)
+		}
+	}
+	// Texture alone has no dominant direction.
+	if m := Analyze(synth(640, 480, 0, 128, 128, 60)); m.TiltConfidence > 0.2 {
+		t.Errorf("no horizon: tilt %.2f, confidence %.2f", m.Tilt, m.TiltConfidence)
+	}
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/steveyiyo/hackyou-backend/internal/core/tips"
+	"github.com/steveyiyo/hackyou-backend/pkg/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CacheHandler struct {
+	// Cache is nil when tip caching is disabled.
+	Cache *tips.Cache
+}
+
+func NewCacheHandler(c *tips.Cache) *CacheHandler {
+	return &CacheHandler{Cache: c}
+}
+
+// Metrics reports the tip cache's size and hit rate.
+func (h *CacheHandler) Metrics(c *gin.Context) {
+	if h.Cache == nil {
+		c.JSON(http.StatusOK, types.CacheMetrics{})
+		return
+	}
+	c.JSON(http.StatusOK, h.Cache.Metrics())
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/steveyiyo/hackyou-backend/internal/core/capture"
+	"github.com/steveyiyo/hackyou-backend/internal/core/tips"
+	"github.com/steveyiyo/hackyou-backend/internal/core/usage"
+	"github.com/steveyiyo/hackyou-backend/internal/core/vision"
+	"github.com/steveyiyo/hackyou-backend/internal/repo"
+	"github.com/steveyiyo/hackyou-backend/pkg/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// DefaultMaxCaptureBytes bounds an uploaded photo when MaxBytes is zero.
+const DefaultMaxCaptureBytes = 20 << 20
+
+type CapturesHandler struct {
+	Repo   repo.SessionRepository
+	Scorer *capture.Scorer
+	// Usage bills the model rating to the session; a session over budget
+	// is scored without it. Nil disables accounting.
+	Usage *usage.Meter
+	// MaxBytes bounds an uploaded photo.
+	MaxBytes int64
+}
+
+func NewCapturesHandler(r repo.SessionRepository, s *capture.Scorer) *CapturesHandler {
+	return &CapturesHandler{Repo: r, Scorer: s, MaxBytes: DefaultMaxCaptureBytes}
+}
+
+// Upload scores the photo in the multipart field "image" and stores the
+// result. The optional field "taken_at" is when the photo was taken, in
+// unix milliseconds.
+func (h *CapturesHandler) Upload(c *gin.Context) {
+	id := c.Param("id")
+	sess, ok := ownSession(c, h.Repo, id)
+	if !ok {
+		return
+	}
+	// Leave room for the multipart framing and form fields.
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.MaxBytes+1<<20)
+	fh, err := c.FormFile("image")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "too_large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad_request"})
+		return
+	}
+	if fh.Size > h.MaxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "too_large"})
+		return
+	}
+	var takenAt int64
+	if v := c.PostForm("taken_at"); v != "" {
+		if takenAt, err = strconv.ParseInt(v, 10, 64); err != nil || takenAt < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "bad_taken_at"})
+			return
+		}
+	}
+	f, err := fh.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad_request"})
+		return
+	}
+	img, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad_request"})
+		return
+	}
+	mime := fh.Header.Get("Content-Type")
+	if mime == "application/octet-stream" {
+		mime = ""
+	}
+
+	ctx := tips.WithSession(c.Request.Context(), tips.Session{Mode: sess.Mode, Locale: sess.Locale})
+	rate := true
+	if h.Usage != nil {
+		ctx = usage.WithRecorder(ctx, func(model string, t usage.Tokens) {
+			h.Repo.AddUsage(id, h.Usage.Add(sess.Owner, model, t))
+		})
+		rate = h.Usage.Check(sess.Owner, sess.Usage) == nil
+	}
+	out, err := h.Scorer.Score(ctx, img, mime, rate)
+	if errors.Is(err, vision.ErrTooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "too_large"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "unsupported_image"})
+		return
+	}
+	out.ID = "cap_" + uuid.NewString()
+	out.T = time.Now().UnixMilli()
+	out.TakenAt = takenAt
+	if !h.Repo.AppendCapture(id, out) {
+		c.JSON(http.StatusConflict, gin.H{"error": "too_many_captures"})
+		return
+	}
+	c.JSON(http.StatusCreated, out)
+}
+
+// List returns the session's captures ranked by score.
+func (h *CapturesHandler) List(c *gin.Context) {
+	sess, ok := ownSession(c, h.Repo, c.Param("id"))
+	if !ok {
+		return
+	}
+	out := types.CapturesResp{Captures: capture.Rank(sess.Captures)}
+	if len(out.Captures) > 0 {
+		out.Best = out.Captures[0].ID
+	}
+	c.JSON(http.StatusOK, out)
+}
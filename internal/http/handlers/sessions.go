@@ -2,21 +2,27 @@ package handlers
 
 import (
 	"net/http"
+	"net/url"
+	"time"
 
+	"github.com/steveyiyo/hackyou-backend/internal/auth"
 	"github.com/steveyiyo/hackyou-backend/internal/core/session"
+	"github.com/steveyiyo/hackyou-backend/internal/repo"
 	"github.com/steveyiyo/hackyou-backend/pkg/types"
 
 	"github.com/gin-gonic/gin"
+	"golang.org/x/text/language"
 )
 
 type SessionsHandler struct {
 	Svc    *session.Service
+	Tokens *auth.StreamTokens
 	Scheme string
 	Host   string
 }
 
-func NewSessionsHandler(svc *session.Service, scheme, host string) *SessionsHandler {
-	return &SessionsHandler{Svc: svc, Scheme: scheme, Host: host}
+func NewSessionsHandler(svc *session.Service, tokens *auth.StreamTokens, scheme, host string) *SessionsHandler {
+	return &SessionsHandler{Svc: svc, Tokens: tokens, Scheme: scheme, Host: host}
 }
 
 func (h *SessionsHandler) Create(c *gin.Context) {
@@ -25,21 +31,98 @@ func (h *SessionsHandler) Create(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "bad_request"})
 		return
 	}
-	sess := h.Svc.Create(req.Mode, req.Locale, req.Device, req.Consent)
-	ws := h.Scheme + "://" + h.Host + "/v1/stream?sess=" + sess.ID
+	if req.Coaching != "" && req.Coaching != types.CoachingInterval && req.Coaching != types.CoachingLive {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad_coaching"})
+		return
+	}
+	if req.Locale != "" {
+		tag, err := language.Parse(req.Locale)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "bad_locale"})
+			return
+		}
+		req.Locale = tag.String()
+	}
+	sess := h.Svc.Create(auth.UserID(c), req)
+	ws, exp, err := h.streamURL(sess.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "token_failed"})
+		return
+	}
 	c.JSON(http.StatusOK, types.CreateSessionResp{
-		SessionID: sess.ID,
-		WSURL:     ws,
-		WebRTC:    map[string]interface{}{"offer_url": "/v1/webrtc/offer"},
+		SessionID:   sess.ID,
+		WSURL:       ws,
+		WSExpiresAt: exp.UnixMilli(),
+		WebRTC:      map[string]interface{}{"offer_url": "/v1/webrtc/offer"},
 	})
 }
 
-func (h *SessionsHandler) Summary(c *gin.Context) {
+// StreamToken issues a fresh ws_url for reconnecting to an existing session.
+func (h *SessionsHandler) StreamToken(c *gin.Context) {
 	id := c.Param("id")
-	sum, ok := h.Svc.Summary(id)
+	sess, ok := ownSession(c, h.Svc.Repo, id)
 	if !ok {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
 		return
 	}
+	if sess.Ended() {
+		c.JSON(http.StatusGone, gin.H{"error": "session_ended"})
+		return
+	}
+	ws, exp, err := h.streamURL(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "token_failed"})
+		return
+	}
+	c.JSON(http.StatusOK, types.StreamTokenResp{WSURL: ws, WSExpiresAt: exp.UnixMilli()})
+}
+
+func (h *SessionsHandler) streamURL(id string) (string, time.Time, error) {
+	tok, exp, err := h.Tokens.Issue(id)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	q := url.Values{"sess": {id}, "token": {tok}}
+	return h.Scheme + "://" + h.Host + "/v1/stream?" + q.Encode(), exp, nil
+}
+
+func (h *SessionsHandler) Summary(c *gin.Context) {
+	id := c.Param("id")
+	if !h.authorize(c, id) {
+		return
+	}
+	sum, _ := h.Svc.Summary(id)
+	c.JSON(http.StatusOK, sum)
+}
+
+func (h *SessionsHandler) End(c *gin.Context) {
+	id := c.Param("id")
+	if !h.authorize(c, id) {
+		return
+	}
+	if !h.Svc.End(id, session.EndClient) {
+		c.JSON(http.StatusConflict, gin.H{"error": "already_ended"})
+		return
+	}
+	sum, _ := h.Svc.Summary(id)
 	c.JSON(http.StatusOK, sum)
 }
+
+func (h *SessionsHandler) authorize(c *gin.Context, id string) bool {
+	_, ok := ownSession(c, h.Svc.Repo, id)
+	return ok
+}
+
+// ownSession writes 404 or 403 and returns false unless session id exists
+// and belongs to the authenticated user.
+func ownSession(c *gin.Context, r repo.SessionRepository, id string) (*types.Session, bool) {
+	sess, ok := r.Get(id)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+		return nil, false
+	}
+	if sess.Owner != auth.UserID(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return nil, false
+	}
+	return sess, true
+}
@@ -4,36 +4,66 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 
+	"github.com/steveyiyo/hackyou-backend/internal/auth"
 	"github.com/steveyiyo/hackyou-backend/internal/core/gemini"
 	"github.com/steveyiyo/hackyou-backend/internal/core/session"
 	"github.com/steveyiyo/hackyou-backend/internal/core/tips"
-	"github.com/steveyiyo/hackyou-backend/internal/repo/memory"
+	"github.com/steveyiyo/hackyou-backend/internal/core/usage"
+	"github.com/steveyiyo/hackyou-backend/internal/core/vision"
+	"github.com/steveyiyo/hackyou-backend/internal/repo"
 	"github.com/steveyiyo/hackyou-backend/pkg/types"
 	"github.com/steveyiyo/hackyou-backend/pkg/ws"
 )
 
 type StreamHandler struct {
-	Hub      *ws.Hub
-	Repo     *memory.SessionRepo
-	Tips     *tips.Engine
-	Sess     *session.Service
-	Gem      *gemini.Client
+	Hub    *ws.Hub
+	Tokens *auth.StreamTokens
+	Repo   repo.SessionRepository
+	Tips   *tips.Engine
+	Sess   *session.Service
+	// Usage accounts model tokens per session and owner and switches a
+	// session to local providers once over budget; nil disables it.
+	Usage *usage.Meter
+	// HistorySize is how many earlier tips providers see; zero sends none.
+	HistorySize int
+	// Schedule configures each stream's tip scheduler.
+	Schedule tips.Schedule
+	// Readiness configures when a stream's shot counts as ready, and
+	// CaptureCountdown how long the client waits before an automatic
+	// shutter.
+	Readiness        tips.Readiness
+	CaptureCountdown time.Duration
+	// Frames sets the accepted image types and the size and quality frames
+	// are stored and sent to providers at.
+	Frames vision.FrameOptions
+	// Motion decides when an unchanged scene reuses the previous tip
+	// instead of asking providers again.
+	Motion tips.Motion
+	// NewLive starts a Gemini Live session for live coaching; nil disables
+	// live coaching.
+	NewLive  func(ctx context.Context) (*gemini.LiveClient, error)
 	Upgrader websocket.Upgrader
 }
 
-func NewStreamHandler(h *ws.Hub, r *memory.SessionRepo, e *tips.Engine, s *session.Service, g *gemini.Client) *StreamHandler {
+func NewStreamHandler(h *ws.Hub, t *auth.StreamTokens, r repo.SessionRepository, e *tips.Engine, s *session.Service) *StreamHandler {
 	return &StreamHandler{
-		Hub:  h,
-		Repo: r,
-		Tips: e,
-		Sess: s,
-		Gem:  g,
+		Hub:    h,
+		Tokens: t,
+		Repo:   r,
+		Tips:   e,
+		Sess:   s,
 		Upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool { return true },
 		},
@@ -46,13 +76,40 @@ func (h *StreamHandler) WS(c *gin.Context) {
 		c.Status(http.StatusBadRequest)
 		return
 	}
-	conn, err := h.Upgrader.Upgrade(c.Writer, c.Request, nil)
+	// The token is only used up once everything else checks out, so a
+	// client can retry a rejected request with the same ws_url.
+	token := c.Query("token")
+	if err := h.Tokens.Check(token, id); err != nil {
+		c.JSON(tokenStatus(err), gin.H{"error": tokenError(err)})
+		return
+	}
+	sess, ok := h.Repo.Get(id)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+		return
+	}
+	if sess.Ended() {
+		c.JSON(http.StatusGone, gin.H{"error": "session_ended"})
+		return
+	}
+	version, ok := negotiateVersion(c.Query("v"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported_version"})
+		return
+	}
+	if err := h.Tokens.Redeem(token, id); err != nil {
+		c.JSON(tokenStatus(err), gin.H{"error": tokenError(err)})
+		return
+	}
+	raw, err := h.Upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
+		h.Tokens.Release(token, id)
 		return
 	}
+	conn := ws.NewConn(raw)
 	h.Hub.Add(id, conn)
 	defer func() {
-		h.Hub.Remove(id)
+		h.Hub.Remove(id, conn)
 		conn.Close()
 	}()
 
@@ -63,118 +120,586 @@ func (h *StreamHandler) WS(c *gin.Context) {
 		return nil
 	})
 
-	_ = conn.WriteJSON(gin.H{"type": "hello", "ts": time.Now().UnixMilli()})
-
-	type frameMsg struct {
-		Type        string `json:"type"`
-		Bytes       string `json:"bytes"`
-		ContentType string `json:"content_type"`
+	sc := &streamConn{
+		h:       h,
+		id:      id,
+		conn:    conn,
+		started: make(chan struct{}, 1),
+		ctrl:    make(chan control, 8),
+		sched:   tips.NewScheduler(h.Schedule),
+		ready:   tips.NewReadyDetector(h.Readiness),
+		motion:  tips.NewMotionDetector(h.Motion),
+	}
+	sc.autoShutter.Store(sess.AutoShutter)
+	coaching := types.CoachingInterval
+	var liveErr error
+	if sess.Coaching == types.CoachingLive {
+		if liveErr = sc.startLive(c.Request.Context()); liveErr == nil {
+			coaching = types.CoachingLive
+			defer sc.live.Close()
+		}
+	}
+	_ = sc.send(types.StreamHello{
+		Type:          types.MsgHello,
+		TS:            time.Now().UnixMilli(),
+		Version:       version,
+		SessionID:     id,
+		Coaching:      coaching,
+		TipIntervalMs: defaultTipInterval.Milliseconds(),
+	})
+	if liveErr != nil {
+		log.Printf("stream %s: live: %v", id, liveErr)
+		sc.sendError("", "live_unavailable", "live coaching unavailable, using interval coaching")
 	}
 
 	done := make(chan struct{})
-	started := make(chan struct{}, 1)
-	startOnce := false
-
 	go func() {
-		for {
-			mt, msg, err := conn.ReadMessage()
+		sc.readLoop()
+		close(done)
+	}()
+	sc.tipLoop(done)
+}
+
+// negotiateVersion picks the protocol version for a client request. An
+// empty request gets the latest version.
+func negotiateVersion(q string) (int, bool) {
+	if q == "" {
+		return types.StreamProtocolMax, true
+	}
+	v, err := strconv.Atoi(q)
+	if err != nil || v < types.StreamProtocolMin {
+		return 0, false
+	}
+	return min(v, types.StreamProtocolMax), true
+}
+
+// streamConn is the per-connection state of a stream session.
+type streamConn struct {
+	h    *StreamHandler
+	id   string
+	conn *ws.Conn
+
+	started chan struct{}
+	ctrl    chan control
+	paused  atomic.Bool
+	// live is set in live coaching mode; while it is connected frames are
+	// forwarded to it and its advice is relayed in place of interval tips.
+	// liveEnded is set once it has stopped for good.
+	live      *gemini.LiveClient
+	liveEnded atomic.Bool
+	// frameTS is the client timestamp of the most recent frame and frameAt
+	// the server time it was received, in unix nanoseconds.
+	frameTS atomic.Int64
+	frameAt atomic.Int64
+	// frameHash is the vision.DHash of the most recent frame. motion
+	// compares it with the frame of last, the latest decision made from a
+	// frame, which only the tip loop uses.
+	frameHash atomic.Uint64
+	motion    *tips.MotionDetector
+	last      tips.Decision
+	// sched drops repeated and low-value tips before they are sent.
+	sched *tips.Scheduler
+	// ready turns tips into capture signals; autoShutter starts from the
+	// session and follows set_auto_shutter for this connection.
+	ready       *tips.ReadyDetector
+	autoShutter atomic.Bool
+}
+
+func (s *streamConn) send(v interface{}) error { return s.conn.Send(v) }
+
+// sendError reports a rejected client message. ref echoes the client's
+// message id, if any.
+func (s *streamConn) sendError(ref, code, msg string) {
+	_ = s.send(types.StreamError{Type: types.MsgError, TS: time.Now().UnixMilli(), Code: code, Message: msg, ID: ref})
+}
+
+const sourceLive = "gemini_live"
+
+const (
+	defaultTipInterval = 2 * time.Second
+	minTipInterval     = 500 * time.Millisecond
+	maxTipInterval     = time.Minute
+)
+
+const (
+	ctrlPause       = "pause"
+	ctrlResume      = "resume"
+	ctrlSetInterval = "set_interval"
+	ctrlTipNow      = "tip_now"
+	// tipFeedback reports whether the user followed the tip sent at TipTS.
+	tipFeedback = "tip_feedback"
+	// setAutoShutter turns the automatic shutter on capture on or off.
+	setAutoShutter = "set_auto_shutter"
+)
+
+// inboundMsg is a JSON message from the client: either a legacy frame or a
+// control message selected by Type.
+type inboundMsg struct {
+	Type        string `json:"type"`
+	ID          string `json:"id"`
+	Bytes       string `json:"bytes"`
+	ContentType string `json:"content_type"`
+	TS          int64  `json:"ts"`
+	IntervalMs  int64  `json:"interval_ms"`
+	TipTS       int64  `json:"tip_ts"`
+	Followed    *bool  `json:"followed"`
+	Enabled     *bool  `json:"enabled"`
+}
+
+type control struct {
+	op       string
+	interval time.Duration
+}
+
+func (s *streamConn) readLoop() {
+	for {
+		mt, msg, err := s.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		s.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+		switch {
+		case mt == websocket.BinaryMessage && (len(msg) == 0 || msg[0] != '{'):
+			f, err := ws.DecodeFrame(msg)
 			if err != nil {
-				close(done)
-				return
+				s.sendError("", "bad_frame", err.Error())
+				continue
 			}
-			if mt != websocket.TextMessage && mt != websocket.BinaryMessage {
+			s.onFrame(f)
+		case mt == websocket.TextMessage || mt == websocket.BinaryMessage:
+			var fm inboundMsg
+			if err := json.Unmarshal(msg, &fm); err != nil {
+				s.sendError("", "bad_message", "invalid JSON")
 				continue
 			}
-			h.Repo.IncFrame(id)
-			var fm frameMsg
-			if json.Unmarshal(msg, &fm) == nil && fm.Bytes != "" && fm.ContentType != "" {
-				if b, err := base64.StdEncoding.DecodeString(fm.Bytes); err == nil {
-					h.Repo.SetFrame(id, fm.ContentType, b)
-				}
+			switch fm.Type {
+			case ctrlPause, ctrlResume, ctrlSetInterval, ctrlTipNow:
+				s.onControl(fm)
+				continue
+			case tipFeedback:
+				s.onFeedback(fm)
+				continue
+			case setAutoShutter:
+				s.onAutoShutter(fm)
+				continue
 			}
-			if !startOnce {
-				startOnce = true
-				select {
-				case started <- struct{}{}:
-				default:
-				}
+			if fm.Bytes == "" || fm.ContentType == "" {
+				s.sendError("", "bad_frame", "bytes and content_type are required")
+				continue
 			}
+			b, err := base64.StdEncoding.DecodeString(fm.Bytes)
+			if err != nil {
+				s.sendError("", "bad_frame", "bytes is not valid base64")
+				continue
+			}
+			s.onFrame(ws.Frame{Type: ws.MsgFrame, ContentType: fm.ContentType, ClientTS: fm.TS, Data: b})
 		}
-	}()
+	}
+}
+
+// onFrame validates and downscales a frame, then makes it the session's
+// latest. Rejected frames are reported and not counted.
+func (s *streamConn) onFrame(f ws.Frame) {
+	p, err := vision.PrepareFrame(f.Data, f.ContentType, s.h.Frames)
+	if err != nil {
+		code := "bad_frame"
+		if errors.Is(err, vision.ErrTypeNotAllowed) {
+			code = "unsupported_type"
+		}
+		s.sendError("", code, err.Error())
+		return
+	}
+	f.ContentType, f.Data = p.ContentType, p.Data
+	s.frameHash.Store(p.Hash)
+	s.h.Repo.IncFrame(s.id)
+	s.h.Repo.SetFrame(s.id, f.ContentType, f.Data)
+	s.frameTS.Store(f.ClientTS)
+	s.frameAt.Store(time.Now().UnixNano())
+	if s.liveOn() && !s.paused.Load() {
+		if err := s.live.SendImageFrame(f.Data, f.ContentType); err != nil {
+			log.Printf("stream %s: live: %v", s.id, err)
+		}
+	}
+	select {
+	case s.started <- struct{}{}:
+	default:
+	}
+}
 
-	interval := 2 * time.Second
+func (s *streamConn) onControl(m inboundMsg) {
+	c := control{op: m.Type}
+	if m.Type == ctrlSetInterval {
+		c.interval = time.Duration(m.IntervalMs) * time.Millisecond
+		if c.interval < minTipInterval || c.interval > maxTipInterval {
+			s.sendError(m.ID, "bad_control", fmt.Sprintf("interval_ms must be between %d and %d", minTipInterval.Milliseconds(), maxTipInterval.Milliseconds()))
+			return
+		}
+	}
+	select {
+	case s.ctrl <- c:
+	default:
+		s.sendError(m.ID, "busy", "too many control messages")
+		return
+	}
+	_ = s.send(types.StreamAck{
+		Type:       types.MsgAck,
+		TS:         time.Now().UnixMilli(),
+		Ack:        m.Type,
+		ID:         m.ID,
+		IntervalMs: c.interval.Milliseconds(),
+	})
+}
+
+func (s *streamConn) onFeedback(m inboundMsg) {
+	if m.TipTS == 0 || m.Followed == nil {
+		s.sendError(m.ID, "bad_control", "tip_ts and followed are required")
+		return
+	}
+	if !s.h.Repo.SetFollowed(s.id, m.TipTS, *m.Followed) {
+		s.sendError(m.ID, "unknown_tip", "no tip was sent at tip_ts")
+		return
+	}
+	_ = s.send(types.StreamAck{Type: types.MsgAck, TS: time.Now().UnixMilli(), Ack: m.Type, ID: m.ID})
+}
+
+func (s *streamConn) onAutoShutter(m inboundMsg) {
+	if m.Enabled == nil {
+		s.sendError(m.ID, "bad_control", "enabled is required")
+		return
+	}
+	s.autoShutter.Store(*m.Enabled)
+	_ = s.send(types.StreamAck{Type: types.MsgAck, TS: time.Now().UnixMilli(), Ack: m.Type, ID: m.ID})
+}
+
+// tipLoop sends a tip every interval once the first frame has arrived,
+// applying control messages between ticks. In live coaching mode tips come
+// from relayLive instead while the Live API is connected, and only tip_now
+// uses the engine; ticks while it is not connected send interval tips.
+func (s *streamConn) tipLoop(done <-chan struct{}) {
+	interval := defaultTipInterval
 	var next time.Time
-	ctx := context.Background()
+	liveWas := s.live != nil
 
 	for {
-		if next.IsZero() {
-			select {
-			case <-done:
-				return
-			case <-started:
-				next = time.Now().Add(interval)
-			}
-			continue
-		}
-		d := time.Until(next)
-		if d < 0 {
-			d = 0
+		var tick <-chan time.Time
+		var timer *time.Timer
+		if !next.IsZero() && !s.paused.Load() {
+			timer = time.NewTimer(max(time.Until(next), 0))
+			tick = timer.C
 		}
-		timer := time.NewTimer(d)
 		select {
 		case <-done:
-			timer.Stop()
 			return
-		case <-timer.C:
-			sess, ok := h.Repo.Get(id)
-			if !ok {
-				return
+		case <-s.started:
+			if next.IsZero() {
+				next = time.Now().Add(interval)
+				s.sendStatus(next, interval)
 			}
-
-			var out types.Tip
-			var respRaw string
-			var usedGemini bool
-
-			if h.Gem != nil && len(sess.LastFrame) > 0 && sess.LastFrameMIM != "" {
-				if tip, raw, err := h.Gem.TipFromImage(ctx, sess.LastFrame, sess.LastFrameMIM); err == nil && tip != nil {
-					out = *tip
-					respRaw = raw
-					usedGemini = true
-				} else {
-					t := h.Tips.DecideTip()
-					out = *t
+		case c := <-s.ctrl:
+			switch c.op {
+			case ctrlPause:
+				s.paused.Store(true)
+			case ctrlResume:
+				if s.paused.Load() && !next.IsZero() {
+					next = time.Now().Add(interval)
+				}
+				s.paused.Store(false)
+			case ctrlSetInterval:
+				interval = c.interval
+				if !next.IsZero() {
+					next = time.Now().Add(interval)
+				}
+			case ctrlTipNow:
+				if !s.sendTip(interval, true) {
+					return
+				}
+				if !next.IsZero() {
+					next = time.Now().Add(interval)
 				}
-			} else {
-				t := h.Tips.DecideTip()
-				out = *t
 			}
-
-			h.Repo.AppendTip(id, out)
-
-			m := gin.H{
-				"type":     "tip",
-				"ts":       out.T,
-				"priority": out.Priority,
-				"text":     out.Text,
-				"hint": gin.H{
-					"yaw_deg":   out.Yaw,
-					"pitch_deg": out.Pitch,
-					"roll_deg":  out.Roll,
-				},
-				"reason": out.Reason,
+			if c.op != ctrlTipNow {
+				s.sendStatus(next, interval)
 			}
-			if usedGemini {
-				m["source"] = "gemini"
-				m["resp"] = respRaw
-			} else {
-				m["source"] = "stub"
+		case <-tick:
+			live := s.liveOn()
+			if liveWas && !live {
+				s.sendError("", "live_unavailable", "live coaching interrupted, using interval coaching")
 			}
-
-			conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
-			if err := conn.WriteJSON(m); err != nil {
+			liveWas = live
+			if !live && !s.sendTip(interval, false) {
 				return
 			}
 			next = next.Add(interval)
 		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+func (s *streamConn) sendStatus(next time.Time, interval time.Duration) {
+	st := types.StreamStatus{Type: types.MsgStatus, TS: time.Now().UnixMilli(), IntervalMs: interval.Milliseconds()}
+	switch {
+	case s.paused.Load():
+		st.State = types.StreamPaused
+	case next.IsZero():
+		st.State = types.StreamWaiting
+	default:
+		st.State = types.StreamStreaming
+	}
+	if sess, ok := s.h.Repo.Get(s.id); ok {
+		st.Frames = sess.Frames
+	}
+	_ = s.send(st)
+}
+
+// sendTip produces one tip for the latest frame and writes it to the client
+// unless the scheduler suppresses it; a forced tip, one the user asked
+// for, is always sent. Producing it may take at most interval, so tips
+// never pile up behind a slow provider; while the scene is unchanged the
+// previous tip is reused instead. It returns false when the stream should
+// stop.
+func (s *streamConn) sendTip(interval time.Duration, force bool) bool {
+	h, id := s.h, s.id
+	ctx, cancel := context.WithTimeout(context.Background(), interval)
+	defer cancel()
+	sess, ok := h.Repo.Get(id)
+	if !ok {
+		return false
+	}
+	if sess.Ended() {
+		_ = s.send(types.StreamBye{Type: types.MsgBye, TS: time.Now().UnixMilli(), Reason: sess.EndReason})
+		return false
+	}
+
+	frameAt, hash := s.frameAt.Load(), s.frameHash.Load()
+	start := time.Now()
+	d, reused := s.reuse(hash, start)
+	if !reused {
+		ctx = tips.WithSession(ctx, tips.Session{
+			Mode:    sess.Mode,
+			Locale:  sess.Locale,
+			History: tips.History(sess.Tips, h.HistorySize, time.Now()),
+		})
+		if frameAt != 0 {
+			ctx = tips.WithFrameHash(ctx, hash)
+		}
+		decide := h.Tips.Decide
+		var overBudget error
+		if h.Usage != nil {
+			ctx = usage.WithRecorder(ctx, func(model string, t usage.Tokens) {
+				h.Repo.AddUsage(id, h.Usage.Add(sess.Owner, model, t))
+			})
+			if overBudget = h.Usage.Check(sess.Owner, sess.Usage); overBudget != nil {
+				decide = h.Tips.DecideLocal
+			}
+		}
+		d = decide(ctx, sess.LastFrame, sess.LastFrameMIM)
+		if d.Err == nil {
+			d.Err = overBudget
+		}
+		// Only a frame the providers fully answered is worth reusing.
+		if frameAt != 0 && d.Err == nil {
+			s.motion.Analyzed(hash, start)
+			s.last = d
+		}
+	}
+	providerMs := time.Since(start).Milliseconds()
+	out, source := *d.Tip, d.Source
+
+	if fire, trigger := s.ready.Observe(out, time.Now()); fire {
+		return s.sendCapture(out, trigger, source) == nil
+	}
+	if tips.IsReadyTip(out) {
+		h.Repo.AppendSuppressed(id, types.SuppressedTip{Tip: out, Reason: tips.SuppressReady})
+		return true
+	}
+	if force {
+		s.sched.Sent(out, time.Now())
+	} else if ok, why := s.sched.Admit(out, time.Now()); !ok {
+		h.Repo.AppendSuppressed(id, types.SuppressedTip{Tip: out, Reason: why})
+		return true
+	}
+	h.Repo.AppendTip(id, out)
+
+	err := s.send(types.StreamTip{
+		Type:     types.MsgTip,
+		TS:       out.T,
+		Priority: out.Priority,
+		Text:     out.Text,
+		Hint:     types.TipHint{Yaw: out.Yaw, Pitch: out.Pitch, Roll: out.Roll},
+		Reason:   out.Reason,
+		Source:   source,
+		Degraded: degradedReason(d.Err),
+		FrameTS:  s.frameTS.Load(),
+	})
+	if err != nil {
+		return false
+	}
+	if frameAt != 0 && !reused {
+		sample := types.LatencySample{
+			Source:       source,
+			FrameToTipMs: time.Since(time.Unix(0, frameAt)).Milliseconds(),
+		}
+		if source != tips.SourceCache {
+			sample.ProviderMs = &providerMs
+		}
+		h.Repo.AppendLatency(id, sample)
+	}
+	return true
+}
+
+// sendCapture tells the client the shot is ready in place of tip t and
+// records the moment on the session timeline.
+func (s *streamConn) sendCapture(t types.Tip, trigger, source string) error {
+	s.h.Repo.AppendTip(s.id, t)
+	s.h.Repo.AppendEvent(s.id, types.TimelineEvent{T: t.T, Kind: types.EventReady, Source: source, Detail: trigger})
+	return s.send(types.StreamCapture{
+		Type:        types.MsgCapture,
+		TS:          t.T,
+		CountdownMs: s.h.CaptureCountdown.Milliseconds(),
+		AutoShutter: s.autoShutter.Load(),
+		Trigger:     trigger,
+		Source:      source,
+		FrameTS:     s.frameTS.Load(),
+	})
+}
+
+// reuse returns the previous decision as a new tip when the frame with
+// hash still shows the scene it was made for, sparing the providers.
+func (s *streamConn) reuse(hash uint64, now time.Time) (tips.Decision, bool) {
+	if s.last.Tip == nil || !s.motion.Unchanged(hash, now) {
+		return tips.Decision{}, false
+	}
+	d := s.last
+	t := *d.Tip
+	t.T = now.UnixMilli()
+	d.Tip = &t
+	s.h.Repo.IncSkipped(s.id)
+	return d, true
+}
+
+// startLive connects the stream to the Live API. The Live API reports no
+// token counts, so live coaching is refused while a token budget is set.
+func (s *streamConn) startLive(ctx context.Context) error {
+	if s.h.NewLive == nil {
+		return errors.New("live coaching not configured")
+	}
+	if s.h.Usage != nil && s.h.Usage.Budgeted() {
+		return errors.New("live coaching is not metered")
+	}
+	lc, err := s.h.NewLive(ctx)
+	if err != nil {
+		return err
+	}
+	advice, err := lc.ReceiveAdvice()
+	if err != nil {
+		lc.Close()
+		return err
+	}
+	s.live = lc
+	go s.relayLive(advice)
+	go func() {
+		// The tip loop tells the client when live coaching stops; the
+		// details are for the server log only.
+		for err := range lc.Errors() {
+			log.Printf("stream %s: live: %v", s.id, err)
+		}
+	}()
+	return nil
+}
+
+// liveOn reports whether tips currently come from the Live API.
+func (s *streamConn) liveOn() bool {
+	return s.live != nil && !s.liveEnded.Load() && s.live.Connected()
+}
+
+// relayLive sends each piece of Live API advice the scheduler admits as a
+// tip as soon as it arrives. Advice received while paused is dropped. Once
+// the advice stops the stream stays on interval tips.
+func (s *streamConn) relayLive(advice <-chan string) {
+	defer s.liveEnded.Store(true)
+	for text := range advice {
+		text = strings.TrimSpace(text)
+		if text == "" || s.paused.Load() {
+			continue
+		}
+		sess, ok := s.h.Repo.Get(s.id)
+		if !ok || sess.Ended() {
+			return
+		}
+		out := types.Tip{T: time.Now().UnixMilli(), Text: text, Priority: types.PriorityNormal, Reason: "live"}
+		if fire, trigger := s.ready.Observe(out, time.Now()); fire {
+			if s.sendCapture(out, trigger, sourceLive) != nil {
+				return
+			}
+			continue
+		}
+		if tips.IsReadyTip(out) {
+			s.h.Repo.AppendSuppressed(s.id, types.SuppressedTip{Tip: out, Reason: tips.SuppressReady})
+			continue
+		}
+		if ok, why := s.sched.Admit(out, time.Now()); !ok {
+			s.h.Repo.AppendSuppressed(s.id, types.SuppressedTip{Tip: out, Reason: why})
+			continue
+		}
+		s.h.Repo.AppendTip(s.id, out)
+		err := s.send(types.StreamTip{
+			Type:     types.MsgTip,
+			TS:       out.T,
+			Priority: out.Priority,
+			Text:     out.Text,
+			Reason:   out.Reason,
+			Source:   sourceLive,
+			FrameTS:  s.frameTS.Load(),
+		})
+		if err != nil {
+			return
+		}
+		if at := s.frameAt.Load(); at != 0 {
+			ms := time.Since(time.Unix(0, at)).Milliseconds()
+			s.h.Repo.AppendLatency(s.id, types.LatencySample{Source: sourceLive, FrameToTipMs: ms})
+		}
+	}
+}
+
+func degradedReason(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, tips.ErrCircuitOpen):
+		return "circuit_open"
+	case errors.Is(err, usage.ErrSessionBudget), errors.Is(err, usage.ErrDailyBudget):
+		return "budget_exceeded"
+	case errors.Is(err, context.DeadlineExceeded):
+		return string(gemini.KindDeadline)
+	default:
+		return string(gemini.KindOf(err))
+	}
+}
+
+func tokenStatus(err error) int {
+	switch err {
+	case auth.ErrTokenSession:
+		return http.StatusForbidden
+	case auth.ErrTokenReplayed:
+		return http.StatusConflict
+	default:
+		return http.StatusUnauthorized
+	}
+}
+
+func tokenError(err error) string {
+	switch err {
+	case auth.ErrTokenMissing:
+		return "token_missing"
+	case auth.ErrTokenExpired:
+		return "token_expired"
+	case auth.ErrTokenSession:
+		return "token_session_mismatch"
+	case auth.ErrTokenReplayed:
+		return "token_replayed"
+	default:
+		return "token_invalid"
 	}
 }
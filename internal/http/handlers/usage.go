@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/steveyiyo/hackyou-backend/internal/auth"
+	"github.com/steveyiyo/hackyou-backend/internal/core/usage"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UsageHandler struct {
+	Meter *usage.Meter
+}
+
+func NewUsageHandler(m *usage.Meter) *UsageHandler {
+	return &UsageHandler{Meter: m}
+}
+
+// Metrics reports model token usage since start and the caller's usage
+// today.
+func (h *UsageHandler) Metrics(c *gin.Context) {
+	c.JSON(http.StatusOK, h.Meter.Metrics(auth.UserID(c)))
+}
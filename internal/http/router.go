@@ -1,15 +1,25 @@
 package http
 
 import (
+	"context"
+	"errors"
 	"os"
+	"time"
 
+	"github.com/steveyiyo/hackyou-backend/internal/auth"
 	"github.com/steveyiyo/hackyou-backend/internal/config"
+	"github.com/steveyiyo/hackyou-backend/internal/core/capture"
 	"github.com/steveyiyo/hackyou-backend/internal/core/gemini"
+	"github.com/steveyiyo/hackyou-backend/internal/core/i18n"
+	"github.com/steveyiyo/hackyou-backend/internal/core/prompts"
 	"github.com/steveyiyo/hackyou-backend/internal/core/session"
 	"github.com/steveyiyo/hackyou-backend/internal/core/tips"
 	ttsprov "github.com/steveyiyo/hackyou-backend/internal/core/tts"
+	"github.com/steveyiyo/hackyou-backend/internal/core/usage"
+	"github.com/steveyiyo/hackyou-backend/internal/core/vision"
 	"github.com/steveyiyo/hackyou-backend/internal/http/handlers"
-	"github.com/steveyiyo/hackyou-backend/internal/repo/memory"
+	"github.com/steveyiyo/hackyou-backend/internal/repo"
+	"github.com/steveyiyo/hackyou-backend/pkg/types"
 	"github.com/steveyiyo/hackyou-backend/pkg/ws"
 
 	"github.com/gin-gonic/gin"
@@ -28,26 +38,80 @@ func cors() gin.HandlerFunc {
 	}
 }
 
-func NewRouter(cfg config.Config) *gin.Engine {
+// heuristicTimeout bounds the offline provider, which runs after the tip's
+// deadline when the model used it up.
+const heuristicTimeout = time.Second
+
+// NewRouter wires the API over the session store sessions, which the
+// caller opens and closes.
+func NewRouter(cfg config.Config, sessions repo.SessionRepository) (*gin.Engine, error) {
+	if cfg.JWTSecret == "" || cfg.JWTSecret == config.DevJWTSecret {
+		if !cfg.AllowDevSecret {
+			return nil, errors.New("JWT_SECRET is unset or the public development secret; set a secret, or JWT_ALLOW_DEV_SECRET=1 for local runs")
+		}
+		cfg.JWTSecret = config.DevJWTSecret
+	}
+
 	r := gin.Default()
 	r.Use(cors())
 
-	repo := memory.NewSessionRepo()
-	svc := session.NewService(repo)
-	engine := tips.New()
+	svc := session.NewService(sessions)
+	svc.TTL = cfg.SessionTTL
+	svc.Idle = cfg.SessionIdle
+	svc.Retention = cfg.SessionRetention
 	hub := ws.NewHub()
+	svc.OnEnd = func(id, reason string) {
+		hub.Close(id, reason, types.StreamBye{Type: types.MsgBye, TS: time.Now().UnixMilli(), Reason: reason})
+	}
+	go svc.RunJanitor(context.Background(), cfg.JanitorInterval)
 	tts := ttsprov.NewGoogleStub(cfg.TTSBase)
 
-	var gclient *gemini.Client
-	if k := os.Getenv("GOOGLE_API_KEY"); k != "" {
-		m := os.Getenv("GEMINI_MODEL")
-		if m == "" {
-			m = "gemini-2.5-flash"
-		}
-		if gc, err := gemini.New(k, m); err == nil {
-			gclient = gc
+	prompt, err := prompts.Load(cfg.PromptDir)
+	if err != nil {
+		return nil, err
+	}
+	var steps []tips.Step
+	var cache *tips.Cache
+	scorer := capture.NewScorer(nil)
+	scorer.Timeout = cfg.CaptureRateTimeout
+	if cfg.GeminiAPIKey != "" {
+		if gc, err := gemini.New(cfg.GeminiAPIKey, cfg.GeminiModel, cfg.GeminiURL); err == nil {
+			gc.Retry = gemini.RetryPolicy{
+				MaxAttempts: cfg.RetryAttempts,
+				Budget:      cfg.RetryBudget,
+				BaseDelay:   cfg.RetryBaseDelay,
+				MaxDelay:    cfg.RetryMaxDelay,
+				Jitter:      cfg.RetryJitter,
+			}
+			gc.Prompts = prompt
+			var provider tips.Provider = gc
+			if cfg.TipCacheSize > 0 {
+				cache = tips.NewCache(gc, tips.CacheConfig{Size: cfg.TipCacheSize, TTL: cfg.TipCacheTTL})
+				provider = cache
+			}
+			steps = append(steps, tips.Step{
+				Name:     "gemini",
+				Provider: provider,
+				Timeout:  cfg.GeminiTimeout,
+				Failures: cfg.BreakerFailures,
+				Cooldown: cfg.BreakerCooldown,
+				Metered:  true,
+			})
+			scorer.Rater = gc
 		}
 	}
+	msgs := i18n.New(cfg.LocaleFallback...)
+	steps = append(steps, tips.Step{
+		Name:     tips.SourceHeuristic,
+		Provider: &tips.Heuristic{Messages: msgs},
+		Timeout:  heuristicTimeout,
+		Local:    true,
+	})
+	engine := tips.New(steps...)
+	engine.Messages = msgs
+	meter := usage.NewMeter(usage.Price{InputPerMTok: cfg.PriceInputPerMTok, OutputPerMTok: cfg.PriceOutputPerMTok})
+	meter.SessionBudget = cfg.SessionTokenBudget
+	meter.DailyBudget = cfg.DailyTokenBudget
 
 	baseScheme := "http"
 	if os.Getenv("TLS") == "1" {
@@ -58,16 +122,56 @@ func NewRouter(cfg config.Config) *gin.Engine {
 		host = "localhost:" + cfg.Port
 	}
 
-	sh := handlers.NewSessionsHandler(svc, baseScheme, host)
-	wsh := handlers.NewStreamHandler(hub, repo, engine, svc, gclient)
+	tokens := auth.NewStreamTokens(cfg.JWTSecret, cfg.StreamTokenTTL)
+	sh := handlers.NewSessionsHandler(svc, tokens, baseScheme, host)
+	wsh := handlers.NewStreamHandler(hub, tokens, sessions, engine, svc)
+	wsh.Usage = meter
+	wsh.HistorySize = cfg.TipHistory
+	wsh.Schedule = tips.Schedule{
+		Cooldown:    cfg.TipCooldown,
+		Similarity:  cfg.TipSimilarity,
+		Hold:        cfg.TipHold,
+		PraiseEvery: cfg.TipPraiseEvery,
+	}
+	wsh.Readiness = tips.Readiness{StableTips: cfg.ReadyStableTips, Cooldown: cfg.ReadyCooldown}
+	wsh.CaptureCountdown = cfg.CaptureCountdown
+	wsh.Frames = vision.FrameOptions{Types: cfg.FrameTypes, MaxDim: cfg.FrameMaxDim, Quality: cfg.FrameQuality}
+	wsh.Motion = tips.Motion{Threshold: cfg.MotionThreshold, MaxReuse: cfg.MotionMaxReuse}
+	if cfg.GeminiAPIKey != "" {
+		wsh.NewLive = func(ctx context.Context) (*gemini.LiveClient, error) {
+			lc := gemini.NewLiveClient(gemini.LiveConfig{
+				URL:          cfg.LiveURL,
+				Model:        cfg.LiveModel,
+				APIKey:       cfg.GeminiAPIKey,
+				SystemPrompt: cfg.LivePrompt,
+				MaxRetries:   cfg.LiveMaxRetries,
+			})
+			if err := lc.StartStreamingSession(ctx); err != nil {
+				return nil, err
+			}
+			return lc, nil
+		}
+	}
 	wh := handlers.NewWebRTCHandler()
 	th := handlers.NewTTSHandler(tts)
+	uh := handlers.NewUsageHandler(meter)
+	cah := handlers.NewCacheHandler(cache)
+	ch := handlers.NewCapturesHandler(sessions, scorer)
+	ch.Usage = meter
+	ch.MaxBytes = cfg.CaptureMaxBytes
 
-	api := r.Group("/v1")
+	api := r.Group("/v1", auth.Middleware(cfg.JWTSecret))
 	api.POST("/sessions", sh.Create)
 	api.GET("/sessions/:id/summary", sh.Summary)
+	api.POST("/sessions/:id/end", sh.End)
+	api.POST("/sessions/:id/stream-token", sh.StreamToken)
+	api.POST("/sessions/:id/captures", ch.Upload)
+	api.GET("/sessions/:id/captures", ch.List)
 	api.POST("/webrtc/offer", wh.Offer)
 	api.POST("/tts", th.Synthesize)
+	api.GET("/metrics/usage", uh.Metrics)
+	api.GET("/metrics/cache", cah.Metrics)
+	// The stream authenticates with the per-session token in ws_url.
 	r.GET("/v1/stream", wsh.WS)
-	return r
+	return r, nil
 }
@@ -0,0 +1,312 @@
+package file
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steveyiyo/hackyou-backend/pkg/types"
+)
+
+const flushInterval = time.Second
+
+// SessionRepo keeps sessions in memory and persists each one as a JSON file
+// under dir. New and ended sessions are written through immediately; other
+// changes are batched and flushed every second, and on Close. The last
+// preview frame is never written to disk. Sessions go in and come out as
+// copies, so callers may read them without holding any lock.
+type SessionRepo struct {
+	dir string
+
+	mu    sync.Mutex
+	m     map[string]*types.Session
+	dirty map[string]struct{}
+
+	// wmu orders disk writes: it is held from taking a snapshot until it
+	// is on disk, so an older snapshot never overwrites a newer one.
+	wmu       sync.Mutex
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func NewSessionRepo(dir string) (*SessionRepo, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	r := &SessionRepo{
+		dir:   dir,
+		m:     map[string]*types.Session{},
+		dirty: map[string]struct{}{},
+		done:  make(chan struct{}),
+	}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	go r.flushLoop()
+	return r, nil
+}
+
+func (r *SessionRepo) Save(s *types.Session) {
+	r.mu.Lock()
+	r.m[s.ID] = s.Clone()
+	r.dirty[s.ID] = struct{}{}
+	r.mu.Unlock()
+	r.flush(s.ID)
+}
+
+func (r *SessionRepo) Get(id string) (*types.Session, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.m[id]
+	if !ok {
+		return nil, false
+	}
+	return s.Clone(), true
+}
+
+// update runs f on session id under the lock and marks it for the next
+// flush when f reports a change. It reports whether the session exists.
+func (r *SessionRepo) update(id string, f func(s *types.Session) bool) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.m[id]
+	if ok && f(s) {
+		r.dirty[id] = struct{}{}
+	}
+	return ok
+}
+
+func (r *SessionRepo) AppendTip(id string, t types.Tip) {
+	r.update(id, func(s *types.Session) bool {
+		if s.Ended() {
+			return false
+		}
+		s.Tips = append(s.Tips, t)
+		return true
+	})
+}
+
+func (r *SessionRepo) SetFollowed(id string, t int64, followed bool) bool {
+	found := false
+	r.update(id, func(s *types.Session) bool {
+		found = s.SetFollowed(t, followed)
+		return found
+	})
+	return found
+}
+
+func (r *SessionRepo) AppendSuppressed(id string, t types.SuppressedTip) {
+	r.update(id, func(s *types.Session) bool {
+		s.AddSuppressed(t)
+		return true
+	})
+}
+
+func (r *SessionRepo) AppendCapture(id string, c types.Capture) bool {
+	added := false
+	r.update(id, func(s *types.Session) bool {
+		added = s.AddCapture(c)
+		return added
+	})
+	return added
+}
+
+func (r *SessionRepo) AppendEvent(id string, e types.TimelineEvent) {
+	r.update(id, func(s *types.Session) bool {
+		s.Timeline = append(s.Timeline, e)
+		return true
+	})
+}
+
+func (r *SessionRepo) IncFrame(id string) {
+	r.update(id, func(s *types.Session) bool {
+		s.Frames++
+		s.LastSeenAt = time.Now()
+		return true
+	})
+}
+
+func (r *SessionRepo) IncSkipped(id string) {
+	r.update(id, func(s *types.Session) bool {
+		s.Skipped++
+		return true
+	})
+}
+
+// SetFrame changes nothing that is written to disk.
+func (r *SessionRepo) SetFrame(id, mime string, b []byte) {
+	r.update(id, func(s *types.Session) bool {
+		s.LastFrame = b
+		s.LastFrameMIM = mime
+		return false
+	})
+}
+
+func (r *SessionRepo) AppendLatency(id string, l types.LatencySample) {
+	r.update(id, func(s *types.Session) bool {
+		s.AddLatency(l)
+		return true
+	})
+}
+
+func (r *SessionRepo) AddUsage(id string, u types.TokenUsage) {
+	r.update(id, func(s *types.Session) bool {
+		s.Usage.Add(u)
+		return true
+	})
+}
+
+func (r *SessionRepo) End(id, reason string, at time.Time) bool {
+	ended := false
+	r.update(id, func(s *types.Session) bool {
+		if s.Ended() {
+			return false
+		}
+		s.EndedAt = at
+		s.EndReason = reason
+		s.LastFrame = nil
+		s.LastFrameMIM = ""
+		ended = true
+		return true
+	})
+	if ended {
+		r.flush(id)
+	}
+	return ended
+}
+
+func (r *SessionRepo) Delete(id string) {
+	r.wmu.Lock()
+	defer r.wmu.Unlock()
+	r.mu.Lock()
+	delete(r.m, id)
+	delete(r.dirty, id)
+	r.mu.Unlock()
+	if err := os.Remove(r.path(id)); err != nil && !os.IsNotExist(err) {
+		log.Printf("session store: remove %s: %v", id, err)
+	}
+}
+
+func (r *SessionRepo) List() []*types.Session {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]*types.Session, 0, len(r.m))
+	for _, s := range r.m {
+		out = append(out, s.Clone())
+	}
+	return out
+}
+
+// Close stops the periodic flush and writes all pending changes. It is
+// safe to call more than once.
+func (r *SessionRepo) Close() error {
+	var err error
+	r.closeOnce.Do(func() {
+		close(r.done)
+		err = r.flush()
+	})
+	return err
+}
+
+func (r *SessionRepo) flushLoop() {
+	t := time.NewTicker(flushInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-t.C:
+			r.flush()
+		}
+	}
+}
+
+// flush writes the dirty sessions among ids, or all dirty sessions when
+// ids is empty. Sessions are copied under r.mu and written outside it, so
+// other sessions are not held up by the disk. It returns the first error.
+func (r *SessionRepo) flush(ids ...string) error {
+	r.wmu.Lock()
+	defer r.wmu.Unlock()
+	r.mu.Lock()
+	if len(ids) == 0 {
+		for id := range r.dirty {
+			ids = append(ids, id)
+		}
+	}
+	snap := make([]*types.Session, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := r.dirty[id]; !ok {
+			continue
+		}
+		delete(r.dirty, id)
+		if s, ok := r.m[id]; ok {
+			snap = append(snap, s.Clone())
+		}
+	}
+	r.mu.Unlock()
+	var first error
+	for _, s := range snap {
+		if err := r.write(s); err != nil {
+			log.Printf("session store: %v", err)
+			// Try again on the next flush.
+			r.mu.Lock()
+			if _, ok := r.m[s.ID]; ok {
+				r.dirty[s.ID] = struct{}{}
+			}
+			r.mu.Unlock()
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
+
+func (r *SessionRepo) load() error {
+	ents, err := os.ReadDir(r.dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range ents {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(r.dir, e.Name()))
+		if err != nil {
+			return err
+		}
+		var s types.Session
+		if err := json.Unmarshal(b, &s); err != nil {
+			log.Printf("session store: skip %s: %v", e.Name(), err)
+			continue
+		}
+		r.m[s.ID] = &s
+	}
+	return nil
+}
+
+// write stores s in its file, replacing it atomically.
+func (r *SessionRepo) write(s *types.Session) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("marshal %s: %w", s.ID, err)
+	}
+	p := r.path(s.ID)
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", s.ID, err)
+	}
+	if err := os.Rename(tmp, p); err != nil {
+		return fmt.Errorf("rename %s: %w", s.ID, err)
+	}
+	return nil
+}
+
+func (r *SessionRepo) path(id string) string {
+	return filepath.Join(r.dir, filepath.Base(id)+".json")
+}
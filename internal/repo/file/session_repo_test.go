@@ -0,0 +1,102 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/steveyiyo/hackyou-backend/pkg/types"
+)
+
+func TestSessionRepoRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	r, err := NewSessionRepo(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	r.Save(&types.Session{ID: "s1", Owner: "u1", CreatedAt: created, Mode: "portrait", Locale: "ja", Device: map[string]string{"os": "ios"}})
+	r.Save(&types.Session{ID: "s2", Owner: "u2", CreatedAt: created})
+	r.AppendTip("s1", types.Tip{T: 1, Text: "Step back."})
+	r.SetFollowed("s1", 1, true)
+	r.IncFrame("s1")
+	r.SetFrame("s1", "image/jpeg", []byte("frame"))
+	provider := int64(80)
+	r.AppendLatency("s1", types.LatencySample{Source: "gemini", FrameToTipMs: 120, ProviderMs: &provider})
+	r.AddUsage("s1", types.TokenUsage{Calls: 1, TotalTokens: 300})
+	r.End("s2", "client", created.Add(time.Minute))
+	r.Delete("s2")
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+
+	r2, err := NewSessionRepo(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r2.Close()
+	if _, ok := r2.Get("s2"); ok {
+		t.Error("deleted session s2 was loaded")
+	}
+	s, ok := r2.Get("s1")
+	if !ok {
+		t.Fatal("session s1 not loaded")
+	}
+	if s.Owner != "u1" || !s.CreatedAt.Equal(created) || s.Mode != "portrait" || s.Locale != "ja" || s.Device["os"] != "ios" {
+		t.Errorf("session = %+v", s)
+	}
+	if len(s.Tips) != 1 || s.Tips[0].Text != "Step back." || s.Tips[0].Followed == nil || !*s.Tips[0].Followed {
+		t.Errorf("tips = %+v", s.Tips)
+	}
+	if s.Frames != 1 || s.Usage.TotalTokens != 300 {
+		t.Errorf("frames = %d, usage = %+v", s.Frames, s.Usage)
+	}
+	if len(s.Latency) != 1 || s.Latency[0].ProviderMs == nil || *s.Latency[0].ProviderMs != 80 {
+		t.Errorf("latency = %+v", s.Latency)
+	}
+	if s.LastFrame != nil || s.LastFrameMIM != "" {
+		t.Error("last frame was written to disk")
+	}
+}
+
+func TestSessionRepoWritesThroughOnEnd(t *testing.T) {
+	dir := t.TempDir()
+	r, err := NewSessionRepo(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	r.Save(&types.Session{ID: "s1"})
+	r.AppendTip("s1", types.Tip{T: 1, Text: "Step back."})
+	r.End("s1", "client", time.Now())
+
+	// Without Close or a flush tick, End alone must have written the file.
+	r2, err := NewSessionRepo(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r2.Close()
+	s, ok := r2.Get("s1")
+	if !ok || !s.Ended() || s.EndReason != "client" || len(s.Tips) != 1 {
+		t.Errorf("session = %+v", s)
+	}
+}
+
+func TestSessionRepoSkipsBadFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "bad.json"), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewSessionRepo(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if n := len(r.List()); n != 0 {
+		t.Errorf("List = %d sessions, want 0", n)
+	}
+}
@@ -7,63 +7,127 @@ import (
 	"github.com/steveyiyo/hackyou-backend/pkg/types"
 )
 
-type Session struct {
-	ID           string
-	CreatedAt    time.Time
-	Mode         string
-	Locale       string
-	Device       map[string]string
-	Consent      map[string]bool
-	Tips         []types.Tip
-	Frames       int64
-	LatencyP50   int64
-	LastFrame    []byte
-	LastFrameMIM string
-}
-
+// SessionRepo keeps sessions in memory. Sessions go in and come out as
+// copies, so callers may read them without holding any lock.
 type SessionRepo struct {
-	m sync.Map
+	mu sync.Mutex
+	m  map[string]*types.Session
 }
 
-func NewSessionRepo() *SessionRepo { return &SessionRepo{} }
+func NewSessionRepo() *SessionRepo { return &SessionRepo{m: map[string]*types.Session{}} }
 
-func (r *SessionRepo) Save(s *Session) { r.m.Store(s.ID, s) }
+func (r *SessionRepo) Save(s *types.Session) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.m[s.ID] = s.Clone()
+}
 
-func (r *SessionRepo) Get(id string) (*Session, bool) {
-	v, ok := r.m.Load(id)
+func (r *SessionRepo) Get(id string) (*types.Session, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.m[id]
 	if !ok {
 		return nil, false
 	}
-	return v.(*Session), true
+	return s.Clone(), true
 }
 
-func (r *SessionRepo) AppendTip(id string, t types.Tip) {
-	v, ok := r.m.Load(id)
-	if !ok {
-		return
+// update runs f on session id under the lock and reports whether it
+// exists.
+func (r *SessionRepo) update(id string, f func(s *types.Session)) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.m[id]
+	if ok {
+		f(s)
 	}
-	s := v.(*Session)
-	s.Tips = append(s.Tips, t)
-	r.m.Store(id, s)
+	return ok
+}
+
+func (r *SessionRepo) AppendTip(id string, t types.Tip) {
+	r.update(id, func(s *types.Session) {
+		if !s.Ended() {
+			s.Tips = append(s.Tips, t)
+		}
+	})
+}
+
+func (r *SessionRepo) SetFollowed(id string, t int64, followed bool) bool {
+	found := false
+	r.update(id, func(s *types.Session) { found = s.SetFollowed(t, followed) })
+	return found
+}
+
+func (r *SessionRepo) AppendSuppressed(id string, t types.SuppressedTip) {
+	r.update(id, func(s *types.Session) { s.AddSuppressed(t) })
+}
+
+func (r *SessionRepo) AppendCapture(id string, c types.Capture) bool {
+	added := false
+	r.update(id, func(s *types.Session) { added = s.AddCapture(c) })
+	return added
+}
+
+func (r *SessionRepo) AppendEvent(id string, e types.TimelineEvent) {
+	r.update(id, func(s *types.Session) { s.Timeline = append(s.Timeline, e) })
 }
 
 func (r *SessionRepo) IncFrame(id string) {
-	v, ok := r.m.Load(id)
-	if !ok {
-		return
-	}
-	s := v.(*Session)
-	s.Frames++
-	r.m.Store(id, s)
+	r.update(id, func(s *types.Session) {
+		s.Frames++
+		s.LastSeenAt = time.Now()
+	})
+}
+
+func (r *SessionRepo) IncSkipped(id string) {
+	r.update(id, func(s *types.Session) { s.Skipped++ })
 }
 
 func (r *SessionRepo) SetFrame(id, mime string, b []byte) {
-	v, ok := r.m.Load(id)
-	if !ok {
-		return
+	r.update(id, func(s *types.Session) {
+		s.LastFrame = b
+		s.LastFrameMIM = mime
+	})
+}
+
+func (r *SessionRepo) AppendLatency(id string, l types.LatencySample) {
+	r.update(id, func(s *types.Session) { s.AddLatency(l) })
+}
+
+func (r *SessionRepo) AddUsage(id string, u types.TokenUsage) {
+	r.update(id, func(s *types.Session) { s.Usage.Add(u) })
+}
+
+func (r *SessionRepo) End(id, reason string, at time.Time) bool {
+	ended := false
+	r.update(id, func(s *types.Session) {
+		if s.Ended() {
+			return
+		}
+		s.EndedAt = at
+		s.EndReason = reason
+		s.LastFrame = nil
+		s.LastFrameMIM = ""
+		ended = true
+	})
+	return ended
+}
+
+func (r *SessionRepo) Delete(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.m, id)
+}
+
+func (r *SessionRepo) List() []*types.Session {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]*types.Session, 0, len(r.m))
+	for _, s := range r.m {
+		out = append(out, s.Clone())
 	}
-	s := v.(*Session)
-	s.LastFrame = b
-	s.LastFrameMIM = mime
-	r.m.Store(id, s)
+	return out
 }
+
+// Close does nothing; sessions do not outlive the process.
+func (r *SessionRepo) Close() error { return nil }
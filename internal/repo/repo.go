@@ -0,0 +1,53 @@
+package repo
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/steveyiyo/hackyou-backend/internal/repo/file"
+	"github.com/steveyiyo/hackyou-backend/internal/repo/memory"
+	"github.com/steveyiyo/hackyou-backend/pkg/types"
+)
+
+// SessionRepository stores sessions. Save keeps a copy of s, and Get and
+// List return copies, which callers may read freely while the session
+// changes.
+type SessionRepository interface {
+	Save(s *types.Session)
+	Get(id string) (*types.Session, bool)
+	AppendTip(id string, t types.Tip)
+	// SetFollowed records whether the user followed the tip sent at t. It
+	// returns false if there is no such tip.
+	SetFollowed(id string, t int64, followed bool) bool
+	IncFrame(id string)
+	// IncSkipped counts a tip made without analysing an unchanged frame.
+	IncSkipped(id string)
+	SetFrame(id, mime string, b []byte)
+	AppendLatency(id string, l types.LatencySample)
+	AppendSuppressed(id string, t types.SuppressedTip)
+	AppendEvent(id string, e types.TimelineEvent)
+	// AppendCapture stores a scored photo; it reports false when the
+	// session is gone or has MaxCaptures.
+	AppendCapture(id string, c types.Capture) bool
+	AddUsage(id string, u types.TokenUsage)
+	// End marks the session ended and drops its last frame. It returns
+	// false if the session does not exist or has already ended.
+	End(id, reason string, at time.Time) bool
+	Delete(id string)
+	List() []*types.Session
+	// Close writes out pending changes; the store must not be used after.
+	Close() error
+}
+
+// Open returns the session store selected by kind ("memory" or "file").
+// dir is only used by the file backend.
+func Open(kind, dir string) (SessionRepository, error) {
+	switch kind {
+	case "", "memory":
+		return memory.NewSessionRepo(), nil
+	case "file":
+		return file.NewSessionRepo(dir)
+	default:
+		return nil, fmt.Errorf("unknown session store %q", kind)
+	}
+}
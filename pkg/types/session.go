@@ -0,0 +1,110 @@
+package types
+
+import (
+	"maps"
+	"slices"
+	"time"
+)
+
+// Session is a coaching session as the session repositories store it.
+type Session struct {
+	ID           string            `json:"id"`
+	Owner        string            `json:"owner"`
+	CreatedAt    time.Time         `json:"created_at"`
+	Mode         string            `json:"mode"`
+	Locale       string            `json:"locale"`
+	Coaching     string            `json:"coaching,omitempty"`
+	Device       map[string]string `json:"device,omitempty"`
+	Consent      map[string]bool   `json:"consent,omitempty"`
+	Tips         []Tip             `json:"tips"`
+	Frames       int64             `json:"frames"`
+	Skipped      int64             `json:"skipped,omitempty"`
+	Latency      []LatencySample   `json:"latency,omitempty"`
+	Usage        TokenUsage        `json:"usage"`
+	Suppressed   []SuppressedTip   `json:"suppressed,omitempty"`
+	AutoShutter  bool              `json:"auto_shutter,omitempty"`
+	Timeline     []TimelineEvent   `json:"timeline,omitempty"`
+	Captures     []Capture         `json:"captures,omitempty"`
+	LastFrame    []byte            `json:"-"`
+	LastFrameMIM string            `json:"-"`
+	LastSeenAt   time.Time         `json:"last_seen_at"`
+	EndedAt      time.Time         `json:"ended_at"`
+	EndReason    string            `json:"end_reason,omitempty"`
+}
+
+// MaxLatencySamples bounds Session.Latency; older samples are dropped.
+const MaxLatencySamples = 1000
+
+// LatencySample is the timing of one tip: from receiving the frame it was
+// based on to sending it, and the provider call alone. ProviderMs is nil
+// for tips no provider was called for, such as cache hits, or whose
+// provider time is not known, such as Live API advice.
+type LatencySample struct {
+	Source       string `json:"source"`
+	FrameToTipMs int64  `json:"frame_to_tip_ms"`
+	ProviderMs   *int64 `json:"provider_ms,omitempty"`
+}
+
+// MaxSuppressed bounds Session.Suppressed; older entries are dropped.
+const MaxSuppressed = 500
+
+// MaxCaptures bounds Session.Captures; further uploads are refused.
+const MaxCaptures = 200
+
+// SuppressedTip is a tip the scheduler did not send, and why.
+type SuppressedTip struct {
+	Tip    Tip    `json:"tip"`
+	Reason string `json:"reason"`
+}
+
+// AddSuppressed appends t, keeping at most MaxSuppressed.
+func (s *Session) AddSuppressed(t SuppressedTip) {
+	s.Suppressed = append(s.Suppressed, t)
+	if n := len(s.Suppressed) - MaxSuppressed; n > 0 {
+		s.Suppressed = append(s.Suppressed[:0], s.Suppressed[n:]...)
+	}
+}
+
+// AddCapture appends c unless the session already has MaxCaptures.
+func (s *Session) AddCapture(c Capture) bool {
+	if len(s.Captures) >= MaxCaptures {
+		return false
+	}
+	s.Captures = append(s.Captures, c)
+	return true
+}
+
+// AddLatency appends l, keeping at most MaxLatencySamples.
+func (s *Session) AddLatency(l LatencySample) {
+	s.Latency = append(s.Latency, l)
+	if n := len(s.Latency) - MaxLatencySamples; n > 0 {
+		s.Latency = append(s.Latency[:0], s.Latency[n:]...)
+	}
+}
+
+// SetFollowed sets Followed on the tip sent at t.
+func (s *Session) SetFollowed(t int64, followed bool) bool {
+	for i := len(s.Tips) - 1; i >= 0; i-- {
+		if s.Tips[i].T == t {
+			s.Tips[i].Followed = &followed
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Session) Ended() bool { return !s.EndedAt.IsZero() }
+
+// Clone returns a copy of s that shares nothing the repositories modify in
+// place. LastFrame is shared; it is replaced, never written to.
+func (s *Session) Clone() *Session {
+	c := *s
+	c.Device = maps.Clone(s.Device)
+	c.Consent = maps.Clone(s.Consent)
+	c.Tips = slices.Clone(s.Tips)
+	c.Latency = slices.Clone(s.Latency)
+	c.Suppressed = slices.Clone(s.Suppressed)
+	c.Timeline = slices.Clone(s.Timeline)
+	c.Captures = slices.Clone(s.Captures)
+	return &c
+}
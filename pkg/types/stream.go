@@ -0,0 +1,121 @@
+package types
+
+//go:generate go run ../../cmd/schemagen -o ../../api/stream.schema.json
+
+// Stream protocol versions understood by the server. Clients request one
+// with the v query parameter on ws_url; the server answers with the highest
+// version it supports that is not above the request, in StreamHello.Version.
+const (
+	StreamProtocolMin = 1
+	StreamProtocolMax = 1
+)
+
+// Server→client message types on /v1/stream.
+const (
+	MsgHello   = "hello"
+	MsgTip     = "tip"
+	MsgCapture = "capture"
+	MsgError   = "error"
+	MsgAck     = "ack"
+	MsgStatus  = "status"
+	MsgBye     = "bye"
+)
+
+// Why a StreamCapture was sent: the model said the shot is ready, or
+// several tips in a row found nothing to improve.
+const (
+	ReadyModel  = "model"
+	ReadyStable = "stable"
+)
+
+// Stream states reported by StreamStatus.
+const (
+	StreamWaiting   = "waiting"
+	StreamStreaming = "streaming"
+	StreamPaused    = "paused"
+)
+
+type StreamHello struct {
+	Type          string `json:"type"`
+	TS            int64  `json:"ts"`
+	Version       int    `json:"version"`
+	SessionID     string `json:"session_id"`
+	Coaching      string `json:"coaching"`
+	TipIntervalMs int64  `json:"tip_interval_ms"`
+}
+
+type TipHint struct {
+	Yaw   float64 `json:"yaw_deg"`
+	Pitch float64 `json:"pitch_deg"`
+	Roll  float64 `json:"roll_deg"`
+}
+
+type StreamTip struct {
+	Type string `json:"type"`
+	TS   int64  `json:"ts"`
+	// Priority is "critical", "high", "normal" or "praise".
+	Priority string  `json:"priority"`
+	Text     string  `json:"text"`
+	Hint     TipHint `json:"hint"`
+	Reason   string  `json:"reason,omitempty"`
+	Source   string  `json:"source"`
+	// Degraded says why a preferred provider was not used, e.g.
+	// "rate_limited", "safety_blocked", "circuit_open" or
+	// "budget_exceeded".
+	Degraded string `json:"degraded,omitempty"`
+	FrameTS  int64  `json:"frame_ts,omitempty"`
+}
+
+// StreamCapture tells the client the shot is ready. With AutoShutter the
+// client should take the photo once CountdownMs has passed.
+type StreamCapture struct {
+	Type        string `json:"type"`
+	TS          int64  `json:"ts"`
+	CountdownMs int64  `json:"countdown_ms"`
+	AutoShutter bool   `json:"auto_shutter"`
+	Trigger     string `json:"trigger"`
+	Source      string `json:"source"`
+	FrameTS     int64  `json:"frame_ts,omitempty"`
+}
+
+type StreamError struct {
+	Type    string `json:"type"`
+	TS      int64  `json:"ts"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	ID      string `json:"id,omitempty"`
+}
+
+type StreamAck struct {
+	Type       string `json:"type"`
+	TS         int64  `json:"ts"`
+	Ack        string `json:"ack"`
+	ID         string `json:"id,omitempty"`
+	IntervalMs int64  `json:"interval_ms,omitempty"`
+}
+
+type StreamStatus struct {
+	Type       string `json:"type"`
+	TS         int64  `json:"ts"`
+	State      string `json:"state"`
+	IntervalMs int64  `json:"interval_ms"`
+	Frames     int64  `json:"frames"`
+}
+
+type StreamBye struct {
+	Type   string `json:"type"`
+	TS     int64  `json:"ts"`
+	Reason string `json:"reason"`
+}
+
+// StreamMessages maps each server→client message type to a zero value of its
+// struct. It drives the JSON Schema generator.
+var StreamMessages = map[string]interface{}{
+	MsgHello:   StreamHello{},
+	MsgTip:     StreamTip{},
+	MsgCapture: StreamCapture{},
+	MsgError:   StreamError{},
+	MsgAck:     StreamAck{},
+	MsgStatus:  StreamStatus{},
+	MsgBye:     StreamBye{},
+}
@@ -1,16 +1,34 @@
 package types
 
 type CreateSessionReq struct {
-	Device  map[string]string `json:"device"`
-	Mode    string            `json:"mode"`
-	Locale  string            `json:"locale"`
-	Consent map[string]bool   `json:"consent"`
+	Device   map[string]string `json:"device"`
+	Mode     string            `json:"mode"`
+	Locale   string            `json:"locale"`
+	Consent  map[string]bool   `json:"consent"`
+	Coaching string            `json:"coaching"`
+	// AutoShutter asks the client to take the photo when a capture
+	// countdown ends.
+	AutoShutter bool `json:"auto_shutter"`
 }
 
+// Coaching styles for CreateSessionReq.Coaching. Interval coaching analyses
+// the latest frame on a timer; live coaching streams frames to the Gemini
+// Live API and relays its advice as it arrives.
+const (
+	CoachingInterval = "interval"
+	CoachingLive     = "live"
+)
+
 type CreateSessionResp struct {
-	SessionID string                 `json:"session_id"`
-	WSURL     string                 `json:"ws_url"`
-	WebRTC    map[string]interface{} `json:"webrtc"`
+	SessionID   string                 `json:"session_id"`
+	WSURL       string                 `json:"ws_url"`
+	WSExpiresAt int64                  `json:"ws_url_expires_at"`
+	WebRTC      map[string]interface{} `json:"webrtc"`
+}
+
+type StreamTokenResp struct {
+	WSURL       string `json:"ws_url"`
+	WSExpiresAt int64  `json:"ws_url_expires_at"`
 }
 
 type TTSReq struct {
@@ -28,6 +46,15 @@ type TTSResp struct {
 	DurationMs int64  `json:"duration_ms"`
 }
 
+// Tip priorities, most urgent first. Critical tips mean the shot is
+// unusable as is; praise needs no change from the user.
+const (
+	PriorityCritical = "critical"
+	PriorityHigh     = "high"
+	PriorityNormal   = "normal"
+	PriorityPraise   = "praise"
+)
+
 type Tip struct {
 	T        int64   `json:"t"`
 	Text     string  `json:"text"`
@@ -36,13 +63,139 @@ type Tip struct {
 	Pitch    float64 `json:"pitch_deg,omitempty"`
 	Roll     float64 `json:"roll_deg,omitempty"`
 	Reason   string  `json:"reason,omitempty"`
+	// Followed is the client's feedback on whether the user followed this
+	// tip; PrevFollowed is the model's judgement of the tip before it.
+	Followed     *bool `json:"followed,omitempty"`
+	PrevFollowed *bool `json:"previous_followed,omitempty"`
+	// Ready is the model's flag that the shot is ready to take.
+	Ready bool `json:"ready,omitempty"`
+}
+
+// Timeline event kinds.
+const (
+	EventReady = "ready"
+)
+
+// TimelineEvent is a notable moment of a session.
+type TimelineEvent struct {
+	T      int64  `json:"t"`
+	Kind   string `json:"kind"`
+	Source string `json:"source,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Capture is a photo uploaded to a session and how well it scored. The
+// component scores are 0-1; Model is the model's 0-10 rating, absent when
+// it was not asked or did not answer. Score, 0-100, combines them.
+type Capture struct {
+	ID          string        `json:"id"`
+	T           int64         `json:"t"`
+	TakenAt     int64         `json:"taken_at,omitempty"`
+	ContentType string        `json:"content_type"`
+	Width       int           `json:"width"`
+	Height      int           `json:"height"`
+	Bytes       int64         `json:"bytes"`
+	Score       float64       `json:"score"`
+	Scores      CaptureScores `json:"scores"`
+	Comment     string        `json:"comment,omitempty"`
+}
+
+// CaptureScores are 0-1 except Model, 0-10; Level is how level the
+// horizon is.
+type CaptureScores struct {
+	Exposure  float64  `json:"exposure"`
+	Sharpness float64  `json:"sharpness"`
+	Level     float64  `json:"level"`
+	Model     *float64 `json:"model,omitempty"`
+}
+
+// CapturesResp lists a session's captures, best first.
+type CapturesResp struct {
+	Captures []Capture `json:"captures"`
+	Best     string    `json:"best,omitempty"`
 }
 
 type SummaryResp struct {
-	SessionID      string `json:"session_id"`
-	LatencyP50Ms   int64  `json:"latency_ms_p50"`
-	FramesAnalyzed int64  `json:"frames_analyzed"`
-	Tips           []Tip  `json:"tips"`
+	SessionID      string         `json:"session_id"`
+	LatencyP50Ms   int64          `json:"latency_ms_p50"`
+	Latency        LatencySummary `json:"latency"`
+	FramesAnalyzed int64          `json:"frames_analyzed"`
+	Tips           []Tip          `json:"tips"`
+	Usage          TokenUsage     `json:"usage"`
+	EndedAt        int64          `json:"ended_at,omitempty"`
+	EndReason      string         `json:"end_reason,omitempty"`
+	// Suppressed counts tips the scheduler withheld, by reason.
+	Suppressed map[string]int  `json:"suppressed,omitempty"`
+	Timeline   []TimelineEvent `json:"timeline,omitempty"`
+	// Skipped counts tips produced without asking providers because the
+	// scene had not changed since the previous analysis.
+	Skipped int64 `json:"analysis_skipped"`
+}
+
+type LatencyStats struct {
+	Count int   `json:"count"`
+	P50   int64 `json:"p50"`
+	P90   int64 `json:"p90"`
+	P99   int64 `json:"p99"`
+	Min   int64 `json:"min"`
+	Max   int64 `json:"max"`
+}
+
+type LatencyBreakdown struct {
+	FrameToTip LatencyStats `json:"frame_to_tip_ms"`
+	Provider   LatencyStats `json:"provider_ms"`
+}
+
+type LatencySummary struct {
+	LatencyBreakdown
+	BySource map[string]LatencyBreakdown `json:"by_source"`
+}
+
+// TokenUsage counts model calls and the tokens they were billed for.
+type TokenUsage struct {
+	Calls        int64   `json:"calls"`
+	PromptTokens int64   `json:"prompt_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	TotalTokens  int64   `json:"total_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+}
+
+func (u *TokenUsage) Add(o TokenUsage) {
+	u.Calls += o.Calls
+	u.PromptTokens += o.PromptTokens
+	u.OutputTokens += o.OutputTokens
+	u.TotalTokens += o.TotalTokens
+	u.CostUSD += o.CostUSD
+}
+
+// OwnerUsage is one user's usage for the current UTC day against their
+// daily budget; zero DailyBudget means unlimited.
+type OwnerUsage struct {
+	Owner       string     `json:"owner"`
+	Day         string     `json:"day"`
+	Usage       TokenUsage `json:"usage"`
+	DailyBudget int64      `json:"daily_budget"`
+}
+
+type UsageMetricsResp struct {
+	Since         int64                 `json:"since"`
+	Total         TokenUsage            `json:"total"`
+	ByModel       map[string]TokenUsage `json:"by_model"`
+	Owner         OwnerUsage            `json:"owner"`
+	SessionBudget int64                 `json:"session_budget"`
+}
+
+// CacheMetrics describes the tip cache since start. Expired entries count
+// as misses too.
+type CacheMetrics struct {
+	Size      int     `json:"size"`
+	Capacity  int     `json:"capacity"`
+	TTLSec    int64   `json:"ttl_sec"`
+	Hits      int64   `json:"hits"`
+	Misses    int64   `json:"misses"`
+	Expired   int64   `json:"expired"`
+	Evictions int64   `json:"evictions"`
+	HitRate   float64 `json:"hit_rate"`
 }
 
 type WebRTCOfferReq struct {
@@ -0,0 +1,92 @@
+package ws
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Binary frame layout (all integers big-endian):
+//
+//	offset  size  field
+//	0       1     protocol version, currently 1
+//	1       1     message type, MsgFrame (1)
+//	2       1     content type: 1 image/jpeg, 2 image/png, 3 image/webp
+//	3       1     flags, reserved, send 0
+//	4       8     client timestamp, unix milliseconds
+//	12      n     raw image bytes
+//
+// A binary message whose first byte is '{' is treated as a legacy JSON frame
+// by the stream handler.
+const (
+	FrameVersion    = 1
+	FrameHeaderSize = 12
+
+	MsgFrame = 1
+)
+
+var contentTypes = map[byte]string{
+	1: "image/jpeg",
+	2: "image/png",
+	3: "image/webp",
+}
+
+var (
+	ErrFrameShort       = errors.New("frame shorter than header")
+	ErrFrameVersion     = errors.New("unsupported frame version")
+	ErrFrameType        = errors.New("unsupported message type")
+	ErrFrameContentType = errors.New("unsupported content type")
+	ErrFrameEmpty       = errors.New("frame has no image data")
+)
+
+type Frame struct {
+	Type        byte
+	ContentType string
+	ClientTS    int64
+	Data        []byte
+}
+
+// DecodeFrame parses a binary stream message. Data aliases b.
+func DecodeFrame(b []byte) (Frame, error) {
+	if len(b) < FrameHeaderSize {
+		return Frame{}, ErrFrameShort
+	}
+	if b[0] != FrameVersion {
+		return Frame{}, ErrFrameVersion
+	}
+	if b[1] != MsgFrame {
+		return Frame{}, ErrFrameType
+	}
+	ct, ok := contentTypes[b[2]]
+	if !ok {
+		return Frame{}, ErrFrameContentType
+	}
+	if len(b) == FrameHeaderSize {
+		return Frame{}, ErrFrameEmpty
+	}
+	return Frame{
+		Type:        b[1],
+		ContentType: ct,
+		ClientTS:    int64(binary.BigEndian.Uint64(b[4:12])),
+		Data:        b[FrameHeaderSize:],
+	}, nil
+}
+
+// EncodeFrame builds a binary stream message for an image.
+func EncodeFrame(contentType string, clientTS int64, data []byte) ([]byte, error) {
+	var code byte
+	for k, v := range contentTypes {
+		if v == contentType {
+			code = k
+		}
+	}
+	if code == 0 {
+		return nil, ErrFrameContentType
+	}
+	b := make([]byte, FrameHeaderSize+len(data))
+	b[0] = FrameVersion
+	b[1] = MsgFrame
+	b[2] = code
+	binary.BigEndian.PutUint64(b[4:12], uint64(clientTS))
+	copy(b[FrameHeaderSize:], data)
+	return b, nil
+}
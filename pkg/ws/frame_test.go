@@ -0,0 +1,49 @@
+package ws
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDecodeFrame(t *testing.T) {
+	b, err := EncodeFrame("image/png", 1700000000123, []byte("img"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := DecodeFrame(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Type != MsgFrame || f.ContentType != "image/png" || f.ClientTS != 1700000000123 || !bytes.Equal(f.Data, []byte("img")) {
+		t.Errorf("DecodeFrame = %+v", f)
+	}
+}
+
+func TestDecodeFrameErrors(t *testing.T) {
+	header := func(version, typ, ct byte) []byte {
+		return []byte{version, typ, ct, 0, 0, 0, 0, 0, 0, 0, 0, 1}
+	}
+	tests := []struct {
+		name string
+		b    []byte
+		want error
+	}{
+		{"empty", nil, ErrFrameShort},
+		{"short", header(FrameVersion, MsgFrame, 1)[:FrameHeaderSize-1], ErrFrameShort},
+		{"version", append(header(2, MsgFrame, 1), 'x'), ErrFrameVersion},
+		{"type", append(header(FrameVersion, 2, 1), 'x'), ErrFrameType},
+		{"content type", append(header(FrameVersion, MsgFrame, 9), 'x'), ErrFrameContentType},
+		{"no data", header(FrameVersion, MsgFrame, 1), ErrFrameEmpty},
+	}
+	for _, tt := range tests {
+		if _, err := DecodeFrame(tt.b); err != tt.want {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestEncodeFrameUnknownType(t *testing.T) {
+	if _, err := EncodeFrame("image/gif", 0, []byte("img")); err != ErrFrameContentType {
+		t.Errorf("err = %v, want %v", err, ErrFrameContentType)
+	}
+}
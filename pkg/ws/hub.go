@@ -2,34 +2,75 @@ package ws
 
 import (
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
 
+const writeTimeout = 5 * time.Second
+
+// Conn serializes JSON writes to a WebSocket so the hub and the stream
+// handler can both send on it.
+type Conn struct {
+	*websocket.Conn
+	wmu sync.Mutex
+}
+
+func NewConn(c *websocket.Conn) *Conn { return &Conn{Conn: c} }
+
+func (c *Conn) Send(v interface{}) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	c.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return c.WriteJSON(v)
+}
+
 type Hub struct {
 	mu    sync.RWMutex
-	conns map[string]*websocket.Conn
+	conns map[string]*Conn
 }
 
 func NewHub() *Hub {
-	return &Hub{conns: map[string]*websocket.Conn{}}
+	return &Hub{conns: map[string]*Conn{}}
 }
 
-func (h *Hub) Add(id string, c *websocket.Conn) {
+func (h *Hub) Add(id string, c *Conn) {
 	h.mu.Lock()
 	h.conns[id] = c
 	h.mu.Unlock()
 }
 
-func (h *Hub) Get(id string) (*websocket.Conn, bool) {
+func (h *Hub) Get(id string) (*Conn, bool) {
 	h.mu.RLock()
 	c, ok := h.conns[id]
 	h.mu.RUnlock()
 	return c, ok
 }
 
-func (h *Hub) Remove(id string) {
+// Remove unregisters id if it still maps to c; a newer connection for the
+// same id is left in place.
+func (h *Hub) Remove(id string, c *Conn) {
 	h.mu.Lock()
+	if h.conns[id] == c {
+		delete(h.conns, id)
+	}
+	h.mu.Unlock()
+}
+
+// Close sends last (if non-nil) and a normal close frame with reason to the
+// connection registered under id, closes it and removes it from the hub.
+func (h *Hub) Close(id, reason string, last interface{}) {
+	h.mu.Lock()
+	c, ok := h.conns[id]
 	delete(h.conns, id)
 	h.mu.Unlock()
+	if !ok {
+		return
+	}
+	if last != nil {
+		_ = c.Send(last)
+	}
+	msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, reason)
+	_ = c.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+	c.Close()
 }